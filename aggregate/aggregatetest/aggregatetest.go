@@ -0,0 +1,296 @@
+// Package aggregatetest provides given / when / then specs for testing aggregates eg:
+//
+//	aggregatetest.New[*account.Account](t).
+//		Given(account.NewAccountOpened{AccountID: id.String()}, account.DepositMade{Amount: 10}).
+//		When(func(a *account.Account) error { return a.Withdraw(5) }).
+//		Then(account.WithdrawalMade{AccountID: id.String(), Amount: 5})
+//
+// Events are compared structurally using reflect.DeepEqual (only the domain events
+// are compared, so generated event IDs and timestamps are ignored) and failures are
+// reported with a diff of the expected and actual events.
+package aggregatetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/aggregate"
+)
+
+// New creates a new spec for aggregates of type T (which needs to be a pointer to a struct)
+func New[T aggregate.Rooter](t testing.TB) *Spec[T] {
+	return &Spec[T]{t: t}
+}
+
+// Spec is a given / when / then spec for aggregates of type T
+type Spec[T aggregate.Rooter] struct {
+	t     testing.TB
+	given []any
+}
+
+// Given sets the events the aggregate is rehydrated from (in order)
+func (s *Spec[T]) Given(events ...any) *Spec[T] {
+	s.given = append(s.given, events...)
+
+	return s
+}
+
+// When rehydrates a fresh aggregate from given events and executes the command
+// against it. The events applied by the command (Events) are the outcome of the spec
+func (s *Spec[T]) When(cmd func(a T) error) *Result {
+	s.t.Helper()
+
+	a, err := newAggregate[T]()
+	if err != nil {
+		s.t.Fatal(err)
+
+		return nil
+	}
+
+	err = rehydrate(a, givenEvents(s.given))
+	if err != nil {
+		s.t.Fatalf("rehydrating aggregate from given events: %v", err)
+
+		return nil
+	}
+
+	err = cmd(a)
+
+	return &Result{
+		t:      s.t,
+		events: payloads(a.Events()),
+		err:    err,
+	}
+}
+
+// WhenExec executes an executor based command function (eg. an application service)
+// against an executor backed by an in memory event store. The given events are stored
+// in the stream of the aggregate rehydrated from them (identified by its StringID),
+// other streams do not exist. The events saved by the command are the outcome of the
+// spec. Executor options (eg. aggregate.WithLoadOrCreate) can be passed in
+func (s *Spec[T]) WhenExec(cmd func(ctx context.Context, exec aggregate.Executor[T]) error, opts ...aggregate.ExecutorOpt) *Result {
+	s.t.Helper()
+
+	es := eventStore{
+		streams: make(map[string][]any),
+	}
+
+	if len(s.given) > 0 {
+		a, err := newAggregate[T]()
+		if err != nil {
+			s.t.Fatal(err)
+
+			return nil
+		}
+
+		err = rehydrate(a, givenEvents(s.given))
+		if err != nil {
+			s.t.Fatalf("rehydrating aggregate from given events: %v", err)
+
+			return nil
+		}
+
+		es.streams[a.StringID()] = append([]any{}, s.given...)
+	}
+
+	exec := aggregate.NewExecutor(aggregate.NewStore[T](&es), opts...)
+
+	err := cmd(context.Background(), exec)
+
+	return &Result{
+		t:      s.t,
+		events: es.appended,
+		err:    err,
+	}
+}
+
+// Result is the outcome of a spec
+type Result struct {
+	t      testing.TB
+	events []any
+	err    error
+}
+
+// Then expects the command to succeed and produce exactly the given events (in order)
+func (r *Result) Then(events ...any) {
+	r.t.Helper()
+
+	if r.err != nil {
+		r.t.Fatalf("expected events, got error: %v", r.err)
+
+		return
+	}
+
+	if events == nil {
+		events = []any{}
+	}
+
+	actual := r.events
+
+	if actual == nil {
+		actual = []any{}
+	}
+
+	if !reflect.DeepEqual(events, actual) {
+		r.t.Errorf("unexpected events (-expected +actual):\n%s", diff(events, actual))
+	}
+}
+
+// ThenNothing expects the command to succeed without producing any events
+func (r *Result) ThenNothing() {
+	r.t.Helper()
+
+	r.Then()
+}
+
+// ThenError expects the command to fail with an error matching err (see errors.Is)
+// and to produce no events
+func (r *Result) ThenError(err error) {
+	r.t.Helper()
+
+	if r.err == nil {
+		r.t.Fatalf("expected error %v, got events: %v", err, r.events)
+
+		return
+	}
+
+	if !errors.Is(r.err, err) {
+		r.t.Fatalf("expected error %v, got: %v", err, r.err)
+
+		return
+	}
+
+	if len(r.events) > 0 {
+		r.t.Errorf("expected no events, got: %v", r.events)
+	}
+}
+
+// diff lists the expected and actual events, marking the ones which differ
+func diff(expected, actual []any) string {
+	var b strings.Builder
+
+	for i := range max(len(expected), len(actual)) {
+		if i < len(expected) && i < len(actual) && reflect.DeepEqual(expected[i], actual[i]) {
+			fmt.Fprintf(&b, "  %#v\n", expected[i])
+
+			continue
+		}
+
+		if i < len(expected) {
+			fmt.Fprintf(&b, "- %#v\n", expected[i])
+		}
+
+		if i < len(actual) {
+			fmt.Fprintf(&b, "+ %#v\n", actual[i])
+		}
+	}
+
+	return b.String()
+}
+
+func newAggregate[T aggregate.Rooter]() (T, error) {
+	t := reflect.TypeFor[T]()
+
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		var zero T
+
+		return zero, fmt.Errorf("%w: %s", aggregate.ErrAggregateRootNotAPointer, t)
+	}
+
+	return reflect.New(t.Elem()).Interface().(T), nil
+}
+
+func rehydrate[T aggregate.Rooter](a T, events []aggregate.Event) error {
+	if r, ok := any(a).(interface {
+		TryRehydrate(acc any, events ...aggregate.Event) error
+	}); ok {
+		return r.TryRehydrate(a, events...)
+	}
+
+	a.Rehydrate(a, events...)
+
+	return nil
+}
+
+func payloads(events []aggregate.Event) []any {
+	p := make([]any, len(events))
+
+	for i, evt := range events {
+		p[i] = evt.E
+	}
+
+	return p
+}
+
+func givenEvents(given []any) []aggregate.Event {
+	events := make([]aggregate.Event, len(given))
+
+	for i, evt := range given {
+		events[i] = aggregate.Event{
+			ID: givenID(i),
+			E:  evt,
+		}
+	}
+
+	return events
+}
+
+func givenID(i int) string {
+	return fmt.Sprintf("given-%d", i+1)
+}
+
+// eventStore is an in memory event store keeping events per stream
+type eventStore struct {
+	mu       sync.Mutex
+	streams  map[string][]any
+	appended []any
+}
+
+// AppendStream appends events, expecting the stream version to be the number of events in the stream
+func (es *eventStore) AppendStream(_ context.Context, id string, version int, events []eventstore.EventToStore) error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if version != len(es.streams[id]) {
+		return eventstore.ErrConcurrencyCheckFailed
+	}
+
+	for _, evt := range events {
+		es.streams[id] = append(es.streams[id], evt.Event)
+		es.appended = append(es.appended, evt.Event)
+	}
+
+	return nil
+}
+
+// ReadStream returns events of the stream
+func (es *eventStore) ReadStream(_ context.Context, id string) ([]eventstore.StoredEvent, error) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	stored := es.streams[id]
+
+	if len(stored) == 0 {
+		return nil, eventstore.ErrStreamNotFound
+	}
+
+	events := make([]eventstore.StoredEvent, len(stored))
+
+	for i, evt := range stored {
+		events[i] = eventstore.StoredEvent{
+			Event:         evt,
+			ID:            givenID(i),
+			Sequence:      uint64(i + 1),
+			StreamID:      id,
+			StreamVersion: i + 1,
+		}
+	}
+
+	return events, nil
+}
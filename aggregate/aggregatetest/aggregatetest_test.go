@@ -0,0 +1,203 @@
+package aggregatetest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aneshas/eventstore/aggregate"
+	"github.com/aneshas/eventstore/aggregate/aggregatetest"
+	"github.com/stretchr/testify/assert"
+)
+
+type id string
+
+func (i id) String() string { return string(i) }
+
+type opened struct {
+	ID string
+}
+
+type deposited struct {
+	Amount int
+}
+
+type withdrawn struct {
+	Amount int
+}
+
+var errInsufficientFunds = errors.New("insufficient funds")
+
+type account struct {
+	aggregate.Root[id]
+
+	balance int
+}
+
+func (a *account) open(accountID string) {
+	a.Apply(opened{ID: accountID})
+}
+
+func (a *account) withdraw(amount int) error {
+	if amount > a.balance {
+		return errInsufficientFunds
+	}
+
+	a.Apply(withdrawn{Amount: amount})
+
+	return nil
+}
+
+func (a *account) Onopened(evt opened) {
+	a.ID = id(evt.ID)
+}
+
+func (a *account) Ondeposited(evt deposited) {
+	a.balance += evt.Amount
+}
+
+func (a *account) Onwithdrawn(evt withdrawn) {
+	a.balance -= evt.Amount
+}
+
+func TestShouldExpectEvents(t *testing.T) {
+	aggregatetest.New[*account](t).
+		Given(opened{ID: "account-1"}, deposited{Amount: 10}).
+		When(func(a *account) error {
+			if err := a.withdraw(3); err != nil {
+				return err
+			}
+
+			return a.withdraw(2)
+		}).
+		Then(withdrawn{Amount: 3}, withdrawn{Amount: 2})
+}
+
+func TestShouldExpectError(t *testing.T) {
+	aggregatetest.New[*account](t).
+		Given(opened{ID: "account-1"}).
+		When(func(a *account) error {
+			return a.withdraw(3)
+		}).
+		ThenError(errInsufficientFunds)
+}
+
+func TestShouldExpectNothing(t *testing.T) {
+	aggregatetest.New[*account](t).
+		Given(opened{ID: "account-1"}).
+		When(func(a *account) error {
+			return nil
+		}).
+		ThenNothing()
+}
+
+func withdraw(accountID string, amount int) func(context.Context, aggregate.Executor[*account]) error {
+	return func(ctx context.Context, exec aggregate.Executor[*account]) error {
+		var a account
+
+		a.ID = id(accountID)
+
+		return exec(ctx, &a, func(ctx context.Context) error {
+			return a.withdraw(amount)
+		})
+	}
+}
+
+func TestShouldExpectEventsSavedByExecutor(t *testing.T) {
+	aggregatetest.New[*account](t).
+		Given(opened{ID: "account-1"}, deposited{Amount: 10}).
+		WhenExec(withdraw("account-1", 4)).
+		Then(withdrawn{Amount: 4})
+}
+
+func TestShouldExpectExecutorError(t *testing.T) {
+	aggregatetest.New[*account](t).
+		WhenExec(withdraw("account-1", 4)).
+		ThenError(aggregate.ErrAggregateNotFound)
+}
+
+func TestShouldKeepGivenEventsInAggregateStream(t *testing.T) {
+	aggregatetest.New[*account](t).
+		Given(opened{ID: "account-1"}, deposited{Amount: 10}).
+		WhenExec(withdraw("account-2", 4)).
+		ThenError(aggregate.ErrAggregateNotFound)
+}
+
+func TestShouldPassExecutorOptions(t *testing.T) {
+	aggregatetest.New[*account](t).
+		WhenExec(func(ctx context.Context, exec aggregate.Executor[*account]) error {
+			var a account
+
+			return exec(ctx, &a, func(ctx context.Context) error {
+				a.open("account-1")
+
+				return nil
+			})
+		}, aggregate.WithCreate()).
+		Then(opened{ID: "account-1"})
+}
+
+type fakeT struct {
+	testing.TB
+
+	failures []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Name() string { return "fake" }
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+}
+
+func TestShouldReportUnexpectedEventsWithDiff(t *testing.T) {
+	var ft fakeT
+
+	aggregatetest.New[*account](&ft).
+		Given(opened{ID: "account-1"}, deposited{Amount: 10}).
+		When(func(a *account) error {
+			return a.withdraw(3)
+		}).
+		Then(withdrawn{Amount: 4})
+
+	assert.Len(t, ft.failures, 1)
+	assert.Equal(
+		t,
+		"unexpected events (-expected +actual):\n"+
+			"- aggregatetest_test.withdrawn{Amount:4}\n"+
+			"+ aggregatetest_test.withdrawn{Amount:3}\n",
+		ft.failures[0],
+	)
+}
+
+func TestShouldReportUnexpectedError(t *testing.T) {
+	var ft fakeT
+
+	aggregatetest.New[*account](&ft).
+		Given(opened{ID: "account-1"}).
+		When(func(a *account) error {
+			return a.withdraw(3)
+		}).
+		Then(withdrawn{Amount: 3})
+
+	assert.Equal(t, []string{"expected events, got error: insufficient funds"}, ft.failures)
+}
+
+func TestShouldReportMissingError(t *testing.T) {
+	var ft fakeT
+
+	aggregatetest.New[*account](&ft).
+		Given(opened{ID: "account-1"}, deposited{Amount: 10}).
+		When(func(a *account) error {
+			return a.withdraw(3)
+		}).
+		ThenError(errInsufficientFunds)
+
+	assert.Equal(t, []string{"expected error insufficient funds, got events: [{3}]"}, ft.failures)
+}
@@ -0,0 +1,202 @@
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrUnexpectedEvent is returned when a stream contains an event which is not
+// of the event type of the decider (see RehydrationError)
+var ErrUnexpectedEvent = errors.New("unexpected event type")
+
+// Decider is a functional alternative to Root which models an aggregate as
+// immutable state S, commands C and events E (usually an interface implemented by all events) eg:
+//
+//	var Account = aggregate.Decider[AccountState, Command, Event]{
+//		InitialState: AccountState{},
+//		Evolve: func(s AccountState, evt Event) AccountState {
+//			switch e := evt.(type) {
+//			case DepositMade:
+//				s.Balance += e.Amount
+//			}
+//
+//			return s
+//		},
+//		Decide: func(s AccountState, cmd Command) ([]Event, error) {
+//			switch c := cmd.(type) {
+//			case Deposit:
+//				return []Event{DepositMade{Amount: c.Amount}}, nil
+//			}
+//
+//			return nil, fmt.Errorf("unknown command %T", cmd)
+//		},
+//	}
+type Decider[S, C, E any] struct {
+	// InitialState is the state of an aggregate with no events
+	InitialState S
+
+	// Evolve returns the state after applying the event
+	Evolve func(state S, evt E) S
+
+	// Decide returns the events resulting from handling the command (or an
+	// error if the command is rejected)
+	Decide func(state S, cmd C) ([]E, error)
+}
+
+// Fold evolves the initial state with the given events
+func (d Decider[S, C, E]) Fold(events ...E) S {
+	return d.evolve(d.InitialState, events)
+}
+
+func (d Decider[S, C, E]) evolve(state S, events []E) S {
+	for _, evt := range events {
+		state = d.Evolve(state, evt)
+	}
+
+	return state
+}
+
+// Decided represents the state of a decider aggregate stream
+type Decided[S any] struct {
+	State S
+
+	// Version is the version of the stream the state was folded from (0 if the stream does not exist)
+	Version int
+
+	firstEventCorrelationID string
+	lastEventID             string
+}
+
+// NewDeciderStore constructs new store of decider aggregates
+func NewDeciderStore[S, C, E any](eventStore EventStore, decider Decider[S, C, E]) *DeciderStore[S, C, E] {
+	return &DeciderStore[S, C, E]{
+		eventStore: eventStore,
+		decider:    decider,
+	}
+}
+
+// DeciderStore loads the state of decider aggregates by folding their event
+// streams and appends decided events with optimistic concurrency
+type DeciderStore[S, C, E any] struct {
+	eventStore EventStore
+	decider    Decider[S, C, E]
+}
+
+// Load loads the state of the aggregate with the given stream id.
+// Initial state (with version 0) is returned if the stream does not exist
+func (s *DeciderStore[S, C, E]) Load(ctx context.Context, id string) (Decided[S], error) {
+	ctx, span := tracing.Tracer().Start(
+		ctx,
+		"aggregate.DeciderStore.Load",
+		trace.WithAttributes(eventstore.AttrStream.String(id)),
+	)
+
+	d, err := s.load(ctx, id)
+	if err == nil {
+		span.SetAttributes(eventstore.AttrVersion.Int(d.Version))
+	}
+
+	return d, tracing.EndSpan(span, err)
+}
+
+func (s *DeciderStore[S, C, E]) load(ctx context.Context, id string) (Decided[S], error) {
+	d := Decided[S]{
+		State: s.decider.InitialState,
+	}
+
+	storedEvents, err := s.eventStore.ReadStream(ctx, id)
+	if err != nil {
+		if errors.Is(err, eventstore.ErrStreamNotFound) {
+			return d, nil
+		}
+
+		return d, err
+	}
+
+	for _, evt := range storedEvents {
+		e, ok := evt.Event.(E)
+		if !ok {
+			return d, &RehydrationError{
+				EventID:   evt.ID,
+				EventType: fmt.Sprintf("%T", evt.Event),
+				Version:   d.Version + 1,
+				Err:       ErrUnexpectedEvent,
+			}
+		}
+
+		d.State = s.decider.Evolve(d.State, e)
+
+		if d.firstEventCorrelationID == "" {
+			d.firstEventCorrelationID = evt.ID
+		}
+
+		d.lastEventID = evt.ID
+		d.Version++
+	}
+
+	return d, nil
+}
+
+// Handle loads the aggregate with the given stream id, decides the command and
+// appends the resulting events expecting the stream not to have changed since it
+// was loaded (eventstore.ErrConcurrencyCheckFailed is returned otherwise).
+// Meta, correlation and causation IDs are set the same way Store does (see CtxWithMeta).
+// It returns the state after the decided events and the events themselves
+func (s *DeciderStore[S, C, E]) Handle(ctx context.Context, id string, cmd C) (S, []E, error) {
+	ctx, span := tracing.Tracer().Start(
+		ctx,
+		"aggregate.DeciderStore.Handle",
+		trace.WithAttributes(eventstore.AttrStream.String(id)),
+	)
+
+	state, events, err := s.handle(ctx, id, cmd)
+	if err == nil {
+		span.SetAttributes(eventstore.AttrEventCount.Int(len(events)))
+	}
+
+	return state, events, tracing.EndSpan(span, err)
+}
+
+func (s *DeciderStore[S, C, E]) handle(ctx context.Context, id string, cmd C) (S, []E, error) {
+	d, err := s.load(ctx, id)
+	if err != nil {
+		return d.State, nil, err
+	}
+
+	decided, err := s.decider.Decide(d.State, cmd)
+	if err != nil {
+		return d.State, nil, err
+	}
+
+	if len(decided) == 0 {
+		return d.State, nil, nil
+	}
+
+	events := make([]Event, len(decided))
+
+	for i, evt := range decided {
+		events[i] = newEvent(uuid.Must(uuid.NewV7()).String(), evt)
+	}
+
+	if d.firstEventCorrelationID == "" {
+		d.firstEventCorrelationID = events[0].ID
+	}
+
+	err = s.eventStore.AppendStream(
+		ctx,
+		id,
+		d.Version,
+		eventsToStore(ctx, d.firstEventCorrelationID, d.lastEventID, events),
+	)
+	if err != nil {
+		return d.State, nil, err
+	}
+
+	return s.decider.evolve(d.State, decided), decided, nil
+}
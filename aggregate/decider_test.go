@@ -0,0 +1,206 @@
+package aggregate_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/aggregate"
+	"github.com/stretchr/testify/assert"
+)
+
+type accountState struct {
+	open    bool
+	balance int
+}
+
+type accountEvent interface{ accountEvent() }
+
+type accountOpened struct{}
+
+type depositMade struct{ amount int }
+
+func (accountOpened) accountEvent() {}
+func (depositMade) accountEvent()   {}
+
+type accountCommand interface{}
+
+type openAccount struct{}
+
+type deposit struct{ amount int }
+
+var errAccountNotOpen = errors.New("account not open")
+
+var accountDecider = aggregate.Decider[accountState, accountCommand, accountEvent]{
+	InitialState: accountState{},
+	Evolve: func(s accountState, evt accountEvent) accountState {
+		switch e := evt.(type) {
+		case accountOpened:
+			s.open = true
+
+		case depositMade:
+			s.balance += e.amount
+		}
+
+		return s
+	},
+	Decide: func(s accountState, cmd accountCommand) ([]accountEvent, error) {
+		switch c := cmd.(type) {
+		case openAccount:
+			if s.open {
+				return nil, nil
+			}
+
+			return []accountEvent{accountOpened{}}, nil
+
+		case deposit:
+			if !s.open {
+				return nil, errAccountNotOpen
+			}
+
+			return []accountEvent{depositMade{amount: c.amount}}, nil
+		}
+
+		return nil, fmt.Errorf("unknown command %T", cmd)
+	},
+}
+
+func TestDeciderShouldFoldEvents(t *testing.T) {
+	s := accountDecider.Fold(accountOpened{}, depositMade{amount: 5}, depositMade{amount: 7})
+
+	assert.Equal(t, accountState{open: true, balance: 12}, s)
+}
+
+func TestDeciderStoreShouldAppendDecidedEventsToNewStream(t *testing.T) {
+	es := eventStore{
+		wantErr: eventstore.ErrStreamNotFound,
+	}
+
+	store := aggregate.NewDeciderStore(&es, accountDecider)
+
+	meta := map[string]string{"foo": "bar"}
+
+	state, events, err := store.Handle(aggregate.CtxWithMeta(context.Background(), meta), "account-1", openAccount{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, accountState{open: true}, state)
+	assert.Equal(t, []accountEvent{accountOpened{}}, events)
+
+	assert.Equal(t, "account-1", es.id)
+	assert.Equal(t, 0, es.version)
+	assert.Len(t, es.eventsToStore, 1)
+	assert.Equal(t, accountOpened{}, es.eventsToStore[0].Event)
+	assert.Equal(t, meta, es.eventsToStore[0].Meta)
+	assert.Equal(t, es.eventsToStore[0].ID, es.eventsToStore[0].CorrelationEventID)
+	assert.Equal(t, es.eventsToStore[0].ID, es.eventsToStore[0].CausationEventID)
+}
+
+func TestDeciderStoreShouldAppendDecidedEventsToExistingStream(t *testing.T) {
+	es := eventStore{
+		storedEvents: []eventstore.StoredEvent{
+			{Event: accountOpened{}, ID: "event-1"},
+			{Event: depositMade{amount: 5}, ID: "event-2"},
+		},
+	}
+
+	store := aggregate.NewDeciderStore(&es, accountDecider)
+
+	state, _, err := store.Handle(context.Background(), "account-1", deposit{amount: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, accountState{open: true, balance: 15}, state)
+
+	assert.Equal(t, 2, es.version)
+	assert.Equal(t, depositMade{amount: 10}, es.eventsToStore[0].Event)
+	assert.Equal(t, "event-1", es.eventsToStore[0].CorrelationEventID)
+	assert.Equal(t, "event-2", es.eventsToStore[0].CausationEventID)
+}
+
+func TestDeciderStoreShouldUseContextCorrelationAndCausationIDs(t *testing.T) {
+	es := eventStore{
+		storedEvents: []eventstore.StoredEvent{
+			{Event: accountOpened{}, ID: "event-1"},
+		},
+	}
+
+	store := aggregate.NewDeciderStore(&es, accountDecider)
+
+	ctx := aggregate.CtxWithCorrelationID(context.Background(), "correlation-id")
+	ctx = aggregate.CtxWithCausationID(ctx, "causation-id")
+
+	_, _, err := store.Handle(ctx, "account-1", deposit{amount: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "correlation-id", es.eventsToStore[0].CorrelationEventID)
+	assert.Equal(t, "causation-id", es.eventsToStore[0].CausationEventID)
+}
+
+func TestDeciderStoreShouldReportRejectedCommand(t *testing.T) {
+	es := eventStore{
+		wantErr: eventstore.ErrStreamNotFound,
+	}
+
+	store := aggregate.NewDeciderStore(&es, accountDecider)
+
+	_, _, err := store.Handle(context.Background(), "account-1", deposit{amount: 10})
+
+	assert.ErrorIs(t, err, errAccountNotOpen)
+	assert.Nil(t, es.eventsToStore)
+}
+
+func TestDeciderStoreShouldReportConcurrencyConflict(t *testing.T) {
+	es := conflictingStore{
+		eventStore: eventStore{
+			storedEvents: []eventstore.StoredEvent{
+				{Event: accountOpened{}, ID: "event-1"},
+			},
+		},
+		conflicts: 1,
+	}
+
+	store := aggregate.NewDeciderStore(&es, accountDecider)
+
+	_, _, err := store.Handle(context.Background(), "account-1", deposit{amount: 10})
+
+	assert.ErrorIs(t, err, eventstore.ErrConcurrencyCheckFailed)
+}
+
+func TestDeciderStoreShouldReportUnexpectedEvents(t *testing.T) {
+	es := eventStore{
+		storedEvents: []eventstore.StoredEvent{
+			{Event: accountOpened{}, ID: "event-1"},
+			{Event: fooEvent{}, ID: "event-2"},
+		},
+	}
+
+	store := aggregate.NewDeciderStore(&es, accountDecider)
+
+	d, err := store.Load(context.Background(), "account-1")
+
+	var rerr *aggregate.RehydrationError
+
+	assert.ErrorAs(t, err, &rerr)
+	assert.ErrorIs(t, err, aggregate.ErrUnexpectedEvent)
+	assert.Equal(t, "event-2", rerr.EventID)
+	assert.Equal(t, 2, rerr.Version)
+	assert.Equal(t, 1, d.Version)
+}
+
+func TestDeciderStoreShouldLoadState(t *testing.T) {
+	es := eventStore{
+		storedEvents: []eventstore.StoredEvent{
+			{Event: accountOpened{}, ID: "event-1"},
+			{Event: depositMade{amount: 3}, ID: "event-2"},
+		},
+	}
+
+	store := aggregate.NewDeciderStore(&es, accountDecider)
+
+	d, err := store.Load(context.Background(), "account-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, accountState{open: true, balance: 3}, d.State)
+	assert.Equal(t, 2, d.Version)
+}
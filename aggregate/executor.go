@@ -2,21 +2,153 @@ package aggregate
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aneshas/eventstore"
 )
 
 // NewExecutor creates a new executor for the given aggregate store.
-func NewExecutor[T Rooter](store *Store[T]) Executor[T] {
+func NewExecutor[T Rooter](store *Store[T], opts ...ExecutorOpt) Executor[T] {
 	return func(ctx context.Context, a T, f func(ctx context.Context) error) error {
-		return Exec(ctx, store, a, f)
+		return Exec(ctx, store, a, f, opts...)
 	}
 }
 
 // Executor is a helper function to load an aggregate from the store, execute a function and save the aggregate back to the store.
 type Executor[T Rooter] func(ctx context.Context, a T, f func(ctx context.Context) error) error
 
+// ErrAggregateAlreadyExists is returned by an executor configured with
+// WithCreate if the aggregate stream already exists
+var ErrAggregateAlreadyExists = errors.New("aggregate already exists")
+
+// ExecutorCfg (configure using ExecutorOpt)
+type ExecutorCfg struct {
+	attempts int
+	backoff  Backoff
+	reset    func(a Rooter) (func(), error)
+	mode     execMode
+}
+
+type execMode int
+
+const (
+	execLoad execMode = iota
+	execLoadOrCreate
+	execCreate
+)
+
+// ExecutorOpt represents executor configuration option
+type ExecutorOpt func(ExecutorCfg) ExecutorCfg
+
+// Backoff returns how long to wait before the given retry attempt (starting at 1)
+type Backoff func(attempt int) time.Duration
+
+// WithRetry is an executor option which retries the command up to attempts
+// times in total if saving the aggregate fails with eventstore.ErrConcurrencyCheckFailed.
+// Before every retry the aggregate is reset in place, reloaded from the store and
+// the command function is executed again against the latest state.
+// The executor resets the embedded root (keeping the ID), while reset needs to bring
+// the domain state back to the state it was passed to the executor in (eg. zero
+// fields, including maps and slices). Backoff can be nil
+func WithRetry[T Rooter](attempts int, backoff Backoff, reset func(a T)) ExecutorOpt {
+	return func(cfg ExecutorCfg) ExecutorCfg {
+		cfg.attempts = attempts
+		cfg.backoff = backoff
+		cfg.reset = func(a Rooter) (func(), error) {
+			if reset == nil {
+				return nil, fmt.Errorf("retry needs a reset function")
+			}
+
+			agg, ok := a.(T)
+			if !ok {
+				return nil, fmt.Errorf("retry reset expects %s aggregate, got %T", reflect.TypeFor[T](), a)
+			}
+
+			return func() { reset(agg) }, nil
+		}
+
+		return cfg
+	}
+}
+
+// WithLoadOrCreate is an executor option which starts from a fresh (rehydrated)
+// aggregate if its stream does not exist yet instead of failing with ErrAggregateNotFound.
+// A new aggregate is saved expecting the stream not to exist, so a concurrently
+// created one results in eventstore.ErrConcurrencyCheckFailed (see WithRetry)
+func WithLoadOrCreate() ExecutorOpt {
+	return func(cfg ExecutorCfg) ExecutorCfg {
+		cfg.mode = execLoadOrCreate
+
+		return cfg
+	}
+}
+
+// WithCreate is an executor option which requires the aggregate to be new.
+// The aggregate is not loaded, the command function is executed against a fresh
+// (rehydrated) aggregate and ErrAggregateAlreadyExists is returned if the stream
+// already exists when saving. The aggregate ID can be set by the command (eg. by an event handler)
+func WithCreate() ExecutorOpt {
+	return func(cfg ExecutorCfg) ExecutorCfg {
+		cfg.mode = execCreate
+
+		return cfg
+	}
+}
+
+// ExponentialBackoff returns a Backoff which doubles the wait time starting
+// from initial, never waiting longer than max
+func ExponentialBackoff(initial, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := initial
+
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+
+		return min(d, max)
+	}
+}
+
 // Exec is a helper function to load an aggregate from the store, execute a function and save the aggregate back to the store.
-func Exec[T Rooter](ctx context.Context, store *Store[T], a T, f func(ctx context.Context) error) error {
-	err := store.ByID(ctx, a.StringID(), a)
+// By default the aggregate needs to exist (see WithLoadOrCreate and WithCreate)
+func Exec[T Rooter](ctx context.Context, store *Store[T], a T, f func(ctx context.Context) error, opts ...ExecutorOpt) error {
+	cfg := ExecutorCfg{
+		attempts: 1,
+	}
+
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+
+	if cfg.attempts <= 1 || cfg.mode == execCreate {
+		return exec(ctx, store, a, f, cfg.mode)
+	}
+
+	reset, err := cfg.reset(a)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := exec(ctx, store, a, f, cfg.mode)
+		if err == nil || !errors.Is(err, eventstore.ErrConcurrencyCheckFailed) || attempt >= cfg.attempts {
+			return err
+		}
+
+		if err := wait(ctx, cfg.backoff, attempt); err != nil {
+			return err
+		}
+
+		reset()
+		resetRoot(a)
+	}
+}
+
+func exec[T Rooter](ctx context.Context, store *Store[T], a T, f func(ctx context.Context) error, mode execMode) error {
+	err := load(ctx, store, a, mode)
 	if err != nil {
 		return err
 	}
@@ -26,5 +158,40 @@ func Exec[T Rooter](ctx context.Context, store *Store[T], a T, f func(ctx contex
 		return err
 	}
 
-	return store.Save(ctx, a)
+	err = store.Save(ctx, a)
+	if mode == execCreate && errors.Is(err, eventstore.ErrConcurrencyCheckFailed) {
+		return fmt.Errorf("%w: %s", ErrAggregateAlreadyExists, a.StringID())
+	}
+
+	return err
+}
+
+func load[T Rooter](ctx context.Context, store *Store[T], a T, mode execMode) error {
+	if mode == execCreate {
+		return rehydrate(a)
+	}
+
+	err := store.ByID(ctx, a.StringID(), a)
+	if mode == execLoadOrCreate && errors.Is(err, ErrAggregateNotFound) {
+		return rehydrate(a)
+	}
+
+	return err
+}
+
+func wait(ctx context.Context, backoff Backoff, attempt int) error {
+	if backoff == nil {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(backoff(attempt))
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+
+	case <-t.C:
+		return nil
+	}
 }
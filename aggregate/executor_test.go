@@ -111,3 +111,352 @@ func TestShould_Report_AggregateNotFound_Error(t *testing.T) {
 
 	assert.ErrorIs(t, err, aggregate.ErrAggregateNotFound)
 }
+
+type incremented struct{}
+
+type counter struct {
+	aggregate.Root[ID]
+
+	n int
+}
+
+// Onincremented handler
+func (c *counter) Onincremented(_ incremented) {
+	c.n++
+}
+
+func resetCounter(c *counter) {
+	c.n = 0
+}
+
+type conflictingStore struct {
+	eventStore
+
+	conflicts int
+	appends   int
+}
+
+// AppendStream fails with a concurrency error (simulating a concurrent writer) until conflicts run out
+func (s *conflictingStore) AppendStream(ctx context.Context, id string, version int, events []eventstore.EventToStore) error {
+	s.appends++
+
+	if s.conflicts > 0 {
+		s.conflicts--
+
+		s.storedEvents = append(s.storedEvents, eventstore.StoredEvent{
+			Event: incremented{},
+			ID:    fmt.Sprintf("concurrent-%d", s.appends),
+		})
+
+		return eventstore.ErrConcurrencyCheckFailed
+	}
+
+	return s.eventStore.AppendStream(ctx, id, version, events)
+}
+
+func TestShould_Retry_On_Concurrency_Conflict(t *testing.T) {
+	es := conflictingStore{
+		eventStore: eventStore{
+			storedEvents: []eventstore.StoredEvent{
+				{Event: incremented{}, ID: "event-1"},
+			},
+		},
+		conflicts: 2,
+	}
+
+	store := aggregate.NewStore[*counter](&es)
+
+	var waits []int
+
+	exec := aggregate.NewExecutor(store, aggregate.WithRetry(3, func(attempt int) time.Duration {
+		waits = append(waits, attempt)
+
+		return time.Millisecond
+	}, resetCounter))
+
+	c := counter{}
+	c.ID = "counter-1"
+
+	var seen []int
+
+	err := exec(context.Background(), &c, func(ctx context.Context) error {
+		seen = append(seen, c.n)
+
+		c.Apply(incremented{})
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, seen)
+	assert.Equal(t, []int{1, 2}, waits)
+	assert.Equal(t, 3, es.appends)
+	assert.Equal(t, 3, es.version)
+	assert.Equal(t, "counter-1", es.id)
+	assert.Len(t, es.eventsToStore, 1)
+	assert.Equal(t, 4, c.n)
+}
+
+func TestShould_Give_Up_After_Retry_Attempts(t *testing.T) {
+	es := conflictingStore{
+		eventStore: eventStore{
+			storedEvents: []eventstore.StoredEvent{
+				{Event: incremented{}, ID: "event-1"},
+			},
+		},
+		conflicts: 5,
+	}
+
+	store := aggregate.NewStore[*counter](&es)
+
+	exec := aggregate.NewExecutor(store, aggregate.WithRetry(2, nil, resetCounter))
+
+	var c counter
+
+	err := exec(context.Background(), &c, func(ctx context.Context) error {
+		c.Apply(incremented{})
+
+		return nil
+	})
+
+	assert.ErrorIs(t, err, eventstore.ErrConcurrencyCheckFailed)
+	assert.Equal(t, 2, es.appends)
+}
+
+func TestShould_Stop_Retrying_On_Context_Cancellation(t *testing.T) {
+	es := conflictingStore{
+		eventStore: eventStore{
+			storedEvents: []eventstore.StoredEvent{
+				{Event: incremented{}, ID: "event-1"},
+			},
+		},
+		conflicts: 5,
+	}
+
+	store := aggregate.NewStore[*counter](&es)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	exec := aggregate.NewExecutor(store, aggregate.WithRetry(5, func(_ int) time.Duration {
+		cancel()
+
+		return time.Minute
+	}, resetCounter))
+
+	var c counter
+
+	err := exec(ctx, &c, func(ctx context.Context) error {
+		c.Apply(incremented{})
+
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, es.appends)
+}
+
+func TestShould_Not_Retry_Other_Errors(t *testing.T) {
+	var es eventStore
+
+	es.storedEvents = []eventstore.StoredEvent{
+		{Event: incremented{}, ID: "event-1"},
+	}
+
+	store := aggregate.NewStore[*counter](&es)
+
+	exec := aggregate.NewExecutor(store, aggregate.WithRetry(3, nil, resetCounter))
+
+	var (
+		c     counter
+		calls int
+	)
+
+	wantErr := fmt.Errorf("error")
+
+	err := exec(context.Background(), &c, func(ctx context.Context) error {
+		calls++
+
+		return wantErr
+	})
+
+	assert.ErrorIs(t, err, wantErr)
+	assert.Equal(t, 1, calls)
+}
+
+type added struct {
+	item string
+}
+
+type cart struct {
+	aggregate.Root[ID]
+
+	items map[string]int
+}
+
+// Onadded handler
+func (c *cart) Onadded(evt added) {
+	c.items[evt.item]++
+}
+
+// Onincremented handler (of the event appended by a concurrent writer)
+func (c *cart) Onincremented(_ incremented) {
+	c.items["concurrent"]++
+}
+
+func newCart(id ID) *cart {
+	c := cart{items: make(map[string]int)}
+	c.ID = id
+
+	return &c
+}
+
+func TestShould_Reset_Aggregate_Before_Retry(t *testing.T) {
+	es := conflictingStore{
+		eventStore: eventStore{
+			storedEvents: []eventstore.StoredEvent{
+				{Event: added{item: "apple"}, ID: "event-1"},
+			},
+		},
+		conflicts: 1,
+	}
+
+	store := aggregate.NewStore[*cart](&es)
+
+	exec := aggregate.NewExecutor(store, aggregate.WithRetry(2, nil, func(c *cart) {
+		c.items = make(map[string]int)
+	}))
+
+	c := newCart("cart-1")
+
+	err := exec(context.Background(), c, func(ctx context.Context) error {
+		c.Apply(added{item: "pear"})
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, es.appends)
+	assert.Equal(t, map[string]int{"apple": 1, "concurrent": 1, "pear": 1}, c.items)
+	assert.Equal(t, 3, c.Version())
+	assert.Equal(t, "event-1", c.FirstEventCorrelationID())
+}
+
+func TestShould_Require_Retry_Reset_Of_Aggregate_Type(t *testing.T) {
+	var es eventStore
+
+	store := aggregate.NewStore[*counter](&es)
+
+	var c counter
+
+	cmd := func(ctx context.Context) error { return nil }
+
+	err := aggregate.NewExecutor(store, aggregate.WithRetry[*counter](2, nil, nil))(context.Background(), &c, cmd)
+
+	assert.Error(t, err)
+
+	err = aggregate.NewExecutor(store, aggregate.WithRetry(2, nil, func(f *foo) { *f = foo{} }))(context.Background(), &c, cmd)
+
+	assert.Error(t, err)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := aggregate.ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+
+	assert.Equal(t, 10*time.Millisecond, backoff(1))
+	assert.Equal(t, 20*time.Millisecond, backoff(2))
+	assert.Equal(t, 40*time.Millisecond, backoff(3))
+	assert.Equal(t, 50*time.Millisecond, backoff(4))
+	assert.Equal(t, 50*time.Millisecond, backoff(10))
+}
+
+func TestShould_Create_Aggregate_If_Not_Found(t *testing.T) {
+	var es eventStore
+
+	es.wantErr = eventstore.ErrStreamNotFound
+
+	store := aggregate.NewStore[*foo](&es)
+
+	exec := aggregate.NewExecutor(store, aggregate.WithLoadOrCreate())
+
+	var f foo
+
+	f.ID = "foo-1"
+
+	err := exec(context.Background(), &f, func(ctx context.Context) error {
+		f.doMoreStuff()
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, es.version)
+	assert.Equal(t, "foo-1", es.id)
+	assert.Len(t, es.eventsToStore, 1)
+}
+
+func TestShould_Load_Existing_Aggregate_With_LoadOrCreate(t *testing.T) {
+	var es eventStore
+
+	es.storedEvents = []eventstore.StoredEvent{
+		{Event: incremented{}, ID: "event-1"},
+	}
+
+	store := aggregate.NewStore[*counter](&es)
+
+	exec := aggregate.NewExecutor(store, aggregate.WithLoadOrCreate())
+
+	var c counter
+
+	err := exec(context.Background(), &c, func(ctx context.Context) error {
+		c.Apply(incremented{})
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, es.version)
+	assert.Equal(t, 2, c.n)
+}
+
+func TestShould_Create_New_Aggregate_Without_Loading(t *testing.T) {
+	var es eventStore
+
+	es.wantErr = fmt.Errorf("should not be loaded")
+
+	store := aggregate.NewStore[*foo](&es)
+
+	exec := aggregate.NewExecutor(store, aggregate.WithCreate())
+
+	var f foo
+
+	err := exec(context.Background(), &f, func(ctx context.Context) error {
+		f.doMoreStuff()
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, es.version)
+	assert.Equal(t, "foo-1", es.id)
+}
+
+func TestShould_Report_AggregateAlreadyExists_Error(t *testing.T) {
+	es := conflictingStore{
+		conflicts: 1,
+	}
+
+	store := aggregate.NewStore[*foo](&es)
+
+	exec := aggregate.NewExecutor(store, aggregate.WithCreate(), aggregate.WithRetry(3, nil, func(f *foo) { *f = foo{} }))
+
+	var f foo
+
+	err := exec(context.Background(), &f, func(ctx context.Context) error {
+		f.doMoreStuff()
+
+		return nil
+	})
+
+	assert.ErrorIs(t, err, aggregate.ErrAggregateAlreadyExists)
+	assert.Equal(t, 1, es.appends)
+}
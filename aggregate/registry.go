@@ -0,0 +1,121 @@
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/aneshas/eventstore/internal/events"
+)
+
+// ErrAggregateNotRegistered is returned when event handlers of an aggregate
+// type have not been registered (see Register)
+var ErrAggregateNotRegistered = errors.New("aggregate event handlers not registered")
+
+// Handler handles events of a single type for aggregates of type A (see On, OnEvent, TryOn and TryOnEvent)
+type Handler[A any] struct {
+	matches func(evt any) bool
+	handle  func(a A, evt Event) error
+}
+
+// On creates a handler of events of type E (which may be a pointer type) for aggregates of type A
+func On[A any, E any](h func(a A, evt E)) Handler[A] {
+	return TryOnEvent(func(a A, evt E, _ Event) error {
+		h(a, evt)
+
+		return nil
+	})
+}
+
+// OnEvent is like On but the handler receives the (wrapping) Event as well
+func OnEvent[A any, E any](h func(a A, evt E, extra Event)) Handler[A] {
+	return TryOnEvent(func(a A, evt E, extra Event) error {
+		h(a, evt, extra)
+
+		return nil
+	})
+}
+
+// TryOn is like On but the handler may return an error in order to reject the event
+func TryOn[A any, E any](h func(a A, evt E) error) Handler[A] {
+	return TryOnEvent(func(a A, evt E, _ Event) error {
+		return h(a, evt)
+	})
+}
+
+// TryOnEvent is like OnEvent but the handler may return an error in order to reject the event
+func TryOnEvent[A any, E any](h func(a A, evt E, extra Event) error) Handler[A] {
+	return Handler[A]{
+		matches: func(evt any) bool {
+			_, ok := events.As[E](evt)
+
+			return ok
+		},
+		handle: func(a A, evt Event) error {
+			e, _ := events.As[E](evt.E)
+
+			return h(a, e, evt)
+		},
+	}
+}
+
+type registry[A any] struct {
+	handlers []Handler[A]
+}
+
+func (r *registry[A]) handler(evt any) (Handler[A], bool) {
+	for _, h := range r.handlers {
+		if h.matches(evt) {
+			return h, true
+		}
+	}
+
+	return Handler[A]{}, false
+}
+
+// registries holds registered handlers per aggregate type
+var registries sync.Map
+
+// Register registers event handlers of aggregates of type A (see TypedRoot).
+// It makes sure that there is a handler for each of the events the aggregate
+// produces (passed in as instances, eg. the same list passed to the encoder),
+// so missing handlers are reported at startup instead of when an event is applied.
+// Register is meant to be called once per aggregate type, eg. in an init function
+func Register[A any](events []any, handlers ...Handler[A]) error {
+	r := registry[A]{
+		handlers: handlers,
+	}
+
+	var missing []error
+
+	for _, evt := range events {
+		if _, ok := r.handler(evt); !ok {
+			missing = append(missing, fmt.Errorf("%w: %T", ErrMissingAggregateEventHandler, evt))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("aggregate %s: %w", reflect.TypeFor[A](), errors.Join(missing...))
+	}
+
+	registries.Store(reflect.TypeFor[A](), &r)
+
+	return nil
+}
+
+// MustRegister is like Register but panics if a handler is missing
+func MustRegister[A any](events []any, handlers ...Handler[A]) {
+	if err := Register(events, handlers...); err != nil {
+		panic(err)
+	}
+}
+
+func registryFor[A any]() (*registry[A], error) {
+	r, ok := registries.Load(reflect.TypeFor[A]())
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAggregateNotRegistered, reflect.TypeFor[A]())
+	}
+
+	return r.(*registry[A]), nil
+}
@@ -18,8 +18,32 @@ var (
 
 	// ErrAggregateRootNotRehydrated is returned when aggregate is not rehydrated (with Rehydrate method)
 	ErrAggregateRootNotRehydrated = fmt.Errorf("aggregate needs to be rehydrated")
+
+	// ErrInvalidAggregateEventHandler is returned when aggregate event handler
+	// has an unexpected signature
+	ErrInvalidAggregateEventHandler = fmt.Errorf("invalid aggregate event handler")
 )
 
+// RehydrationError is returned when an aggregate cannot be rehydrated
+// because the handler of one of its events failed
+type RehydrationError struct {
+	EventID   string
+	EventType string
+
+	// Version is the aggregate version the event would have produced
+	Version int
+
+	Err error
+}
+
+// Error returns error message
+func (e *RehydrationError) Error() string {
+	return fmt.Sprintf("rehydrating aggregate: event %s (%s) at version %d: %v", e.EventID, e.EventType, e.Version, e.Err)
+}
+
+// Unwrap returns the handler error
+func (e *RehydrationError) Unwrap() error { return e.Err }
+
 // Rooter represents an aggregate root interface
 type Rooter interface {
 	StringID() string
@@ -63,7 +87,8 @@ func (a *Root[T]) StringID() string {
 	return a.ID.String()
 }
 
-// Rehydrate is used to construct and rehydrate the aggregate from events
+// Rehydrate is used to construct and rehydrate the aggregate from events.
+// Values returned by event handlers are ignored (see TryRehydrate)
 func (a *Root[T]) Rehydrate(aggregatePtr any, events ...Event) {
 	a.ptr = reflect.ValueOf(aggregatePtr)
 
@@ -71,12 +96,46 @@ func (a *Root[T]) Rehydrate(aggregatePtr any, events ...Event) {
 		panic(ErrAggregateRootNotAPointer)
 	}
 
+	must(a.rehydrate(events, a.mutate))
+}
+
+// TryRehydrate is like Rehydrate but returns an error instead of panicking.
+// Event handlers need to have one of the signatures described in Apply and
+// may return an error, in which case RehydrationError is returned
+func (a *Root[T]) TryRehydrate(aggregatePtr any, events ...Event) error {
+	ptr := reflect.ValueOf(aggregatePtr)
+
+	if ptr.Kind() != reflect.Pointer {
+		return ErrAggregateRootNotAPointer
+	}
+
+	a.ptr = ptr
+
+	return a.rehydrate(events, a.tryMutate)
+}
+
+func (a *Root[T]) rehydrate(events []Event, mutate func(Event) error) error {
 	for _, evt := range events {
-		a.mutate(evt)
+		correlationID := a.firstEventCorrelationID
+
+		a.correlate(evt)
+
+		if err := mutate(evt); err != nil {
+			a.firstEventCorrelationID = correlationID
+
+			return &RehydrationError{
+				EventID:   evt.ID,
+				EventType: fmt.Sprintf("%T", evt.E),
+				Version:   a.version + 1,
+				Err:       err,
+			}
+		}
 
 		a.lastEventID = evt.ID
 		a.version++
 	}
+
+	return nil
 }
 
 // Version returns current version of the aggregate (incremented every time
@@ -102,21 +161,34 @@ func (a *Root[T]) Events() []Event {
 // func (a *SomeAggregate) OnSomethingImportantHappened(event SomethingImportantHappened) error
 // or
 // func (a *SomeAggregate) OnSomethingImportantHappened(event SomethingImportantHappened, extra aggregate.Event) error
+//
+// Apply panics if the handler is missing and ignores values returned by the handler (see TryApply)
 func (a *Root[T]) Apply(events ...any) {
 	if !a.ptr.IsValid() {
 		panic(ErrAggregateRootNotRehydrated)
 	}
 
 	for _, evt := range events {
-		e := Event{
-			ID:         uuid.Must(uuid.NewV7()).String(),
-			E:          evt,
-			OccurredOn: time.Now().UTC(),
-		}
+		must(a.apply(newEvent(uuid.Must(uuid.NewV7()).String(), evt), a.mutate))
+	}
+}
+
+// TryApply is like Apply but returns an error instead of panicking.
+// Event handlers need to have one of the signatures described in Apply and
+// may return an error in order to reject the event, in which case
+// the event is not recorded and the remaining events are not applied
+func (a *Root[T]) TryApply(events ...any) error {
+	if !a.ptr.IsValid() {
+		return ErrAggregateRootNotRehydrated
+	}
 
-		a.mutate(e)
-		a.appendEvent(e)
+	for _, evt := range events {
+		if err := a.apply(newEvent(uuid.Must(uuid.NewV7()).String(), evt), a.tryMutate); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
 // ApplyWithID applies single event and mutates aggregate (calls respective event handle) and sets event ID explicitly.
@@ -126,58 +198,152 @@ func (a *Root[T]) ApplyWithID(eventID string, event any) {
 		panic(ErrAggregateRootNotRehydrated)
 	}
 
-	e := Event{
-		ID:         eventID,
-		E:          event,
-		OccurredOn: time.Now().UTC(),
+	must(a.apply(newEvent(eventID, event), a.mutate))
+}
+
+// TryApplyWithID is like ApplyWithID but returns an error instead of panicking (see TryApply)
+func (a *Root[T]) TryApplyWithID(eventID string, event any) error {
+	if !a.ptr.IsValid() {
+		return ErrAggregateRootNotRehydrated
 	}
 
-	a.mutate(e)
-	a.appendEvent(e)
+	return a.apply(newEvent(eventID, event), a.tryMutate)
+}
+
+func newEvent(id string, evt any) Event {
+	return Event{
+		ID:         id,
+		E:          evt,
+		OccurredOn: time.Now().UTC(),
+	}
 }
 
-func (a *Root[T]) mutate(evt Event) {
-	ev := reflect.TypeOf(evt.E)
-	h := a.handler(fmt.Sprintf("On%s", ev.Name()))
+func (a *Root[T]) apply(evt Event, mutate func(Event) error) error {
+	correlationID := a.firstEventCorrelationID
+
+	a.correlate(evt)
+
+	if err := mutate(evt); err != nil {
+		a.firstEventCorrelationID = correlationID
 
+		return err
+	}
+
+	a.appendEvent(evt)
+
+	return nil
+}
+
+func (a *Root[T]) correlate(evt Event) {
 	if a.firstEventCorrelationID == "" {
 		a.firstEventCorrelationID = evt.ID
 	}
+}
 
+// mutate calls the event handler ignoring its return values
+func (a *Root[T]) mutate(evt Event) error {
+	h, ok := a.handler(reflect.TypeOf(evt.E).Name())
+	if !ok {
+		panic(ErrMissingAggregateEventHandler)
+	}
+
+	a.call(h, evt)
+
+	return nil
+}
+
+// tryMutate calls the event handler, checking its signature and returning its error
+func (a *Root[T]) tryMutate(evt Event) error {
+	name := reflect.TypeOf(evt.E).Name()
+
+	h, ok := a.handler(name)
+	if !ok {
+		return fmt.Errorf("%w: On%s", ErrMissingAggregateEventHandler, name)
+	}
+
+	t := h.Type()
+
+	if t.NumIn() == 0 || t.NumIn() > 2 ||
+		!reflect.TypeOf(evt.E).AssignableTo(t.In(0)) ||
+		(t.NumIn() == 2 && t.In(1) != eventType) ||
+		t.NumOut() > 1 ||
+		(t.NumOut() == 1 && t.Out(0) != errorType) {
+		return fmt.Errorf("%w: On%s has unexpected signature %s", ErrInvalidAggregateEventHandler, name, t)
+	}
+
+	out := a.call(h, evt)
+
+	if len(out) == 1 {
+		if err, ok := out[0].Interface().(error); ok && err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Root[T]) call(h reflect.Value, evt Event) []reflect.Value {
 	if h.Type().NumIn() == 2 {
-		h.Call([]reflect.Value{
+		return h.Call([]reflect.Value{
 			reflect.ValueOf(evt.E),
 			reflect.ValueOf(evt),
 		})
-
-		return
 	}
 
-	h.Call([]reflect.Value{
+	return h.Call([]reflect.Value{
 		reflect.ValueOf(evt.E),
 	})
 }
 
-func (a *Root[T]) handler(name string) reflect.Value {
+var (
+	eventType = reflect.TypeFor[Event]()
+	errorType = reflect.TypeFor[error]()
+)
+
+func (a *Root[T]) handler(eventName string) (reflect.Value, bool) {
+	name := fmt.Sprintf("On%s", eventName)
+
 	if a.handlers == nil {
 		a.handlers = make(map[string]reflect.Value)
 	}
 
 	if h, ok := a.handlers[name]; ok {
-		return h
+		return h, true
 	}
 
 	h := a.ptr.MethodByName(name)
 
 	if !h.IsValid() {
-		panic(ErrMissingAggregateEventHandler)
+		return reflect.Value{}, false
 	}
 
 	a.handlers[name] = h
 
-	return h
+	return h, true
+}
+
+// markCommitted clears uncommitted events, advancing the version and last event ID
+func (a *Root[T]) markCommitted() {
+	if len(a.domainEvents) == 0 {
+		return
+	}
+
+	a.version += len(a.domainEvents)
+	a.lastEventID = a.domainEvents[len(a.domainEvents)-1].ID
+	a.domainEvents = nil
+}
+
+// reset clears the root state (version, events and rehydration) keeping only the ID
+func (a *Root[T]) reset() {
+	*a = Root[T]{ID: a.ID}
 }
 
 func (a *Root[T]) appendEvent(evt Event) {
 	a.domainEvents = append(a.domainEvents, evt)
 }
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
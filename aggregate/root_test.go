@@ -206,3 +206,119 @@ func TestShouldAcceptOnlyPointerOnRehydration(t *testing.T) {
 
 	a.Rehydrate(a)
 }
+
+type rejected struct{}
+
+type rejectingAggregate struct {
+	aggregate.Root[id]
+
+	applied int
+}
+
+var errRejected = errors.New("rejected")
+
+func (ra *rejectingAggregate) Onrejected(_ rejected) error {
+	return errRejected
+}
+
+func (ra *rejectingAggregate) Oncreated(_ created) error {
+	ra.applied++
+
+	return nil
+}
+
+func TestTryApplyShouldReturnHandlerError(t *testing.T) {
+	var a rejectingAggregate
+
+	assert.NoError(t, a.TryRehydrate(&a))
+
+	err := a.TryApply(created{}, rejected{}, created{})
+
+	assert.ErrorIs(t, err, errRejected)
+	assert.Equal(t, 1, a.applied)
+	assert.Len(t, a.Events(), 1)
+}
+
+func TestTryApplyWithIDShouldNotRecordRejectedEvent(t *testing.T) {
+	var a rejectingAggregate
+
+	assert.NoError(t, a.TryRehydrate(&a))
+
+	err := a.TryApplyWithID("event-id", rejected{})
+
+	assert.ErrorIs(t, err, errRejected)
+	assert.Empty(t, a.Events())
+	assert.Empty(t, a.FirstEventCorrelationID())
+}
+
+func TestApplyShouldIgnoreHandlerErrors(t *testing.T) {
+	var a rejectingAggregate
+
+	a.Rehydrate(&a, aggregate.Event{ID: "event-1", E: rejected{}})
+
+	a.Apply(rejected{}, created{})
+	a.ApplyWithID("event-id", rejected{})
+
+	assert.Equal(t, 1, a.Version())
+	assert.Equal(t, 1, a.applied)
+	assert.Len(t, a.Events(), 3)
+	assert.Equal(t, "event-1", a.FirstEventCorrelationID())
+}
+
+type correlated struct{}
+
+type correlatingAggregate struct {
+	aggregate.Root[id]
+
+	correlationID string
+}
+
+func (ca *correlatingAggregate) Oncorrelated(_ correlated) {
+	ca.correlationID = ca.FirstEventCorrelationID()
+}
+
+func TestHandlerShouldSeeCorrelationIDOfFirstEvent(t *testing.T) {
+	var a correlatingAggregate
+
+	a.Rehydrate(&a)
+	a.ApplyWithID("event-1", correlated{})
+
+	assert.Equal(t, "event-1", a.correlationID)
+
+	var b correlatingAggregate
+
+	assert.NoError(t, b.TryRehydrate(&b, aggregate.Event{ID: "event-2", E: correlated{}}))
+	assert.Equal(t, "event-2", b.correlationID)
+}
+
+func TestTryApplyShouldReturnErrors(t *testing.T) {
+	var a testAggregate
+
+	assert.ErrorIs(t, a.TryApply(created{}), aggregate.ErrAggregateRootNotRehydrated)
+	assert.ErrorIs(t, a.TryApplyWithID("id", created{}), aggregate.ErrAggregateRootNotRehydrated)
+	assert.ErrorIs(t, a.TryRehydrate(a), aggregate.ErrAggregateRootNotAPointer)
+
+	assert.NoError(t, a.TryRehydrate(&a))
+
+	assert.ErrorIs(t, a.TryApply(missingHandler{}), aggregate.ErrMissingAggregateEventHandler)
+	assert.ErrorIs(t, a.TryApply(wrongHandler{}), aggregate.ErrInvalidAggregateEventHandler)
+}
+
+func TestTryRehydrateShouldReturnRehydrationError(t *testing.T) {
+	var a rejectingAggregate
+
+	err := a.TryRehydrate(
+		&a,
+		aggregate.Event{ID: "event-1", E: created{}},
+		aggregate.Event{ID: "event-2", E: rejected{}},
+	)
+
+	var rerr *aggregate.RehydrationError
+
+	assert.ErrorAs(t, err, &rerr)
+	assert.ErrorIs(t, err, errRejected)
+	assert.Equal(t, "event-2", rerr.EventID)
+	assert.Equal(t, "aggregate_test.rejected", rerr.EventType)
+	assert.Equal(t, 2, rerr.Version)
+	assert.Equal(t, 1, a.Version())
+}
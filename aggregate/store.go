@@ -5,6 +5,9 @@ import (
 	"errors"
 
 	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/internal/tracing"
+	"github.com/aneshas/tx/v2"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ErrAggregateNotFound is returned when aggregate is not found
@@ -34,8 +37,84 @@ type Store[T Rooter] struct {
 	eventStore EventStore
 }
 
-// Save saves aggregate events to the event store
+// Save saves aggregate events to the event store.
+// Once saved, the events of aggregates embedding Root (or TypedRoot) are marked
+// as committed (Events is cleared and Version and LastEventID advanced), so the same
+// aggregate instance can be used to process further commands.
+// If ctx carries a transaction (eg. started with tx.TX) the aggregate is not marked
+// as committed, since the transaction can still be rolled back. Its events are
+// kept, so in order to process further commands once the transaction is committed
+// the aggregate needs to be reloaded (see UnitOfWork)
 func (s *Store[T]) Save(ctx context.Context, aggregate T) error {
+	err := s.append(ctx, aggregate)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := tx.From[tx.Transaction](ctx); ok {
+		return nil
+	}
+
+	markCommitted(aggregate)
+
+	return nil
+}
+
+func (s *Store[T]) append(ctx context.Context, aggregate T) error {
+	ctx, span := tracing.Tracer().Start(
+		ctx,
+		"aggregate.Store.Save",
+		trace.WithAttributes(
+			eventstore.AttrStream.String(aggregate.StringID()),
+			eventstore.AttrVersion.Int(aggregate.Version()),
+			eventstore.AttrEventCount.Int(len(aggregate.Events())),
+		),
+	)
+
+	events := eventsToStore(
+		ctx,
+		aggregate.FirstEventCorrelationID(),
+		aggregate.LastEventID(),
+		aggregate.Events(),
+	)
+
+	err := s.eventStore.AppendStream(
+		ctx,
+		aggregate.StringID(),
+		aggregate.Version(),
+		events,
+	)
+
+	return tracing.EndSpan(span, err)
+}
+
+func markCommitted(aggregate Rooter) {
+	if c, ok := aggregate.(committer); ok {
+		c.markCommitted()
+	}
+}
+
+// committer is implemented by roots which keep track of uncommitted events (eg. Root)
+type committer interface {
+	markCommitted()
+}
+
+func resetRoot(aggregate Rooter) {
+	if r, ok := aggregate.(resetter); ok {
+		r.reset()
+	}
+}
+
+// resetter is implemented by roots which can be brought back to their initial state (eg. Root)
+type resetter interface {
+	reset()
+}
+
+// eventsToStore converts aggregate events to events to store, setting their meta,
+// correlation and causation IDs (see CtxWithMeta, CtxWithCorrelationID and CtxWithCausationID).
+// Unless set in the context, the correlation ID is the ID of the first aggregate event and
+// the causation ID of each event is the ID of the event preceding it
+func eventsToStore(ctx context.Context, firstEventCorrelationID, lastEventID string, evts []Event) []eventstore.EventToStore {
 	var (
 		events        []eventstore.EventToStore
 		meta          map[string]string
@@ -56,14 +135,14 @@ func (s *Store[T]) Save(ctx context.Context, aggregate T) error {
 	}
 
 	if correlationID == "" {
-		correlationID = aggregate.FirstEventCorrelationID()
+		correlationID = firstEventCorrelationID
 	}
 
 	if causationID == "" {
-		causationID = aggregate.LastEventID()
+		causationID = lastEventID
 	}
 
-	for _, evt := range aggregate.Events() {
+	for _, evt := range evts {
 		if causationID == "" {
 			causationID = evt.ID
 		}
@@ -80,16 +159,27 @@ func (s *Store[T]) Save(ctx context.Context, aggregate T) error {
 		causationID = evt.ID
 	}
 
-	return s.eventStore.AppendStream(
+	return events
+}
+
+// ByID finds aggregate events by its stream id and rehydrates the aggregate.
+// If an event handler fails during rehydration RehydrationError is returned
+func (s *Store[T]) ByID(ctx context.Context, id string, root T) error {
+	ctx, span := tracing.Tracer().Start(
 		ctx,
-		aggregate.StringID(),
-		aggregate.Version(),
-		events,
+		"aggregate.Store.ByID",
+		trace.WithAttributes(eventstore.AttrStream.String(id)),
 	)
+
+	err := s.byID(ctx, id, root)
+	if err == nil {
+		span.SetAttributes(eventstore.AttrVersion.Int(root.Version()))
+	}
+
+	return tracing.EndSpan(span, err)
 }
 
-// ByID finds aggregate events by its stream id and rehydrates the aggregate
-func (s *Store[T]) ByID(ctx context.Context, id string, root T) error {
+func (s *Store[T]) byID(ctx context.Context, id string, root T) error {
 	storedEvents, err := s.eventStore.ReadStream(ctx, id)
 	if err != nil {
 		if errors.Is(err, eventstore.ErrStreamNotFound) {
@@ -112,11 +202,25 @@ func (s *Store[T]) ByID(ctx context.Context, id string, root T) error {
 		})
 	}
 
+	return rehydrate(root, events...)
+}
+
+func rehydrate[T Rooter](root T, events ...Event) error {
+	if r, ok := any(root).(tryRehydrater); ok {
+		return r.TryRehydrate(root, events...)
+	}
+
 	root.Rehydrate(root, events...)
 
 	return nil
 }
 
+// tryRehydrater is implemented by roots which report rehydration failures
+// as errors (eg. Root and TypedRoot) instead of panicking
+type tryRehydrater interface {
+	TryRehydrate(acc any, events ...Event) error
+}
+
 // CtxWithMeta returns new context with meta data
 func CtxWithMeta(ctx context.Context, meta map[string]string) context.Context {
 	return context.WithValue(ctx, metaKey{}, meta)
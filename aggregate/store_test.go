@@ -84,6 +84,8 @@ func (f *foo) OnfooEvent(evt fooEvent) {
 	f.ID = ID(evt.Foo)
 }
 
+type ctxKey struct{}
+
 func TestShould_Save_Aggregate_Events(t *testing.T) {
 	var es eventStore
 
@@ -94,24 +96,25 @@ func TestShould_Save_Aggregate_Events(t *testing.T) {
 	}
 
 	ctx := aggregate.CtxWithMeta(context.Background(), meta)
+	ctx = context.WithValue(ctx, ctxKey{}, "value")
 
 	var f foo
 
 	f.Rehydrate(&f)
 	f.doStuff()
 
+	events := f.Events()
+
 	err := store.Save(ctx, &f)
 
 	assert.NoError(t, err)
 
 	assert.Equal(t, meta, es.eventsToStore[0].Meta)
 
-	assert.Equal(t, ctx, es.ctx)
+	assert.Equal(t, "value", es.ctx.Value(ctxKey{}))
 	assert.Equal(t, 0, es.version)
 	assert.Equal(t, "foo-2", es.id)
 
-	events := f.Events()
-
 	assert.Equal(t, []eventstore.EventToStore{
 		{
 			Event: fooEvent{
@@ -154,12 +157,12 @@ func TestShould_Save_Aggregate_Events_With_Explicit_CorrelationIDs(t *testing.T)
 	f.Rehydrate(&f)
 	f.doStuff()
 
+	events := f.Events()
+
 	err := store.Save(ctx, &f)
 
 	assert.NoError(t, err)
 
-	events := f.Events()
-
 	assert.Equal(t, []eventstore.EventToStore{
 		{
 			Event: fooEvent{
@@ -243,3 +246,54 @@ func TestShould_Rehydrate_Aggregate(t *testing.T) {
 	assert.Equal(t, 2, f.Version())
 	assert.Len(t, f.Events(), 0)
 }
+
+func TestShould_Mark_Events_Committed_On_Save(t *testing.T) {
+	var es eventStore
+
+	store := aggregate.NewStore[*foo](&es)
+
+	var f foo
+
+	f.Rehydrate(&f)
+	f.doStuff()
+
+	events := f.Events()
+
+	err := store.Save(context.Background(), &f)
+
+	assert.NoError(t, err)
+	assert.Empty(t, f.Events())
+	assert.Equal(t, 2, f.Version())
+	assert.Equal(t, events[1].ID, f.LastEventID())
+	assert.Equal(t, events[0].ID, f.FirstEventCorrelationID())
+
+	f.doMoreStuff()
+
+	err = store.Save(context.Background(), &f)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, es.version)
+	assert.Len(t, es.eventsToStore, 1)
+	assert.Equal(t, events[1].ID, es.eventsToStore[0].CausationEventID)
+	assert.Equal(t, events[0].ID, es.eventsToStore[0].CorrelationEventID)
+	assert.Equal(t, 3, f.Version())
+}
+
+func TestShould_Not_Mark_Events_Committed_On_Save_Error(t *testing.T) {
+	es := conflictingStore{
+		conflicts: 1,
+	}
+
+	store := aggregate.NewStore[*foo](&es)
+
+	var f foo
+
+	f.Rehydrate(&f)
+	f.doStuff()
+
+	err := store.Save(context.Background(), &f)
+
+	assert.ErrorIs(t, err, eventstore.ErrConcurrencyCheckFailed)
+	assert.Len(t, f.Events(), 2)
+	assert.Equal(t, 0, f.Version())
+}
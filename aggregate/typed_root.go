@@ -0,0 +1,134 @@
+package aggregate
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// TypedRoot is an alternative to Root which dispatches events to handlers
+// registered once per aggregate type (see Register) instead of finding
+// On{EventName} methods using reflection. A is the aggregate (pointer) type eg:
+//
+//	type Account struct {
+//		aggregate.TypedRoot[ID, *Account]
+//	}
+//
+//	func init() {
+//		aggregate.MustRegister(
+//			Events,
+//			aggregate.On(func(a *Account, evt AccountOpened) { a.ID = ParseID(evt.AccountID) }),
+//			aggregate.On(func(a *Account, evt DepositMade) { a.Balance += evt.Amount }),
+//		)
+//	}
+type TypedRoot[T fmt.Stringer, A any] struct {
+	Root[T]
+
+	aggregate A
+	registry  *registry[A]
+}
+
+// Rehydrate is used to construct and rehydrate the aggregate from events
+// (aggregatePtr needs to be of aggregate type A).
+// Errors returned by event handlers are ignored (see TryRehydrate)
+func (a *TypedRoot[T, A]) Rehydrate(aggregatePtr any, events ...Event) {
+	must(a.init(aggregatePtr))
+	must(a.rehydrate(events, a.mutate))
+}
+
+// TryRehydrate is like Rehydrate but returns an error instead of panicking.
+// If an event handler fails, RehydrationError is returned
+func (a *TypedRoot[T, A]) TryRehydrate(aggregatePtr any, events ...Event) error {
+	if err := a.init(aggregatePtr); err != nil {
+		return err
+	}
+
+	return a.rehydrate(events, a.tryMutate)
+}
+
+func (a *TypedRoot[T, A]) init(aggregatePtr any) error {
+	agg, ok := aggregatePtr.(A)
+	if !ok {
+		return fmt.Errorf("%w: got %T", ErrAggregateRootNotAPointer, aggregatePtr)
+	}
+
+	r, err := registryFor[A]()
+	if err != nil {
+		return err
+	}
+
+	a.aggregate = agg
+	a.registry = r
+
+	return nil
+}
+
+// Apply mutates aggregate (calls respective registered event handler) and
+// appends event to internal slice, so that they can be retrieved with Events method.
+// It panics if the handler is missing and ignores errors returned by the handler (see TryApply)
+func (a *TypedRoot[T, A]) Apply(events ...any) {
+	if a.registry == nil {
+		panic(ErrAggregateRootNotRehydrated)
+	}
+
+	for _, evt := range events {
+		must(a.apply(newEvent(uuid.Must(uuid.NewV7()).String(), evt), a.mutate))
+	}
+}
+
+// TryApply is like Apply but returns an error instead of panicking.
+// Handlers registered with TryOn or TryOnEvent may return an error in order
+// to reject the event, in which case the event is not recorded and the
+// remaining events are not applied
+func (a *TypedRoot[T, A]) TryApply(events ...any) error {
+	if a.registry == nil {
+		return ErrAggregateRootNotRehydrated
+	}
+
+	for _, evt := range events {
+		if err := a.apply(newEvent(uuid.Must(uuid.NewV7()).String(), evt), a.tryMutate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ApplyWithID applies single event and sets event ID explicitly. See Apply for more details
+func (a *TypedRoot[T, A]) ApplyWithID(eventID string, event any) {
+	if a.registry == nil {
+		panic(ErrAggregateRootNotRehydrated)
+	}
+
+	must(a.apply(newEvent(eventID, event), a.mutate))
+}
+
+// TryApplyWithID is like ApplyWithID but returns an error instead of panicking (see TryApply)
+func (a *TypedRoot[T, A]) TryApplyWithID(eventID string, event any) error {
+	if a.registry == nil {
+		return ErrAggregateRootNotRehydrated
+	}
+
+	return a.apply(newEvent(eventID, event), a.tryMutate)
+}
+
+// mutate calls the event handler ignoring its error
+func (a *TypedRoot[T, A]) mutate(evt Event) error {
+	h, ok := a.registry.handler(evt.E)
+	if !ok {
+		panic(fmt.Errorf("%w: %T", ErrMissingAggregateEventHandler, evt.E))
+	}
+
+	_ = h.handle(a.aggregate, evt)
+
+	return nil
+}
+
+func (a *TypedRoot[T, A]) tryMutate(evt Event) error {
+	h, ok := a.registry.handler(evt.E)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrMissingAggregateEventHandler, evt.E)
+	}
+
+	return h.handle(a.aggregate, evt)
+}
@@ -0,0 +1,268 @@
+package aggregate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/aggregate"
+	"github.com/stretchr/testify/assert"
+)
+
+type opened struct {
+	name string
+}
+
+type renamed struct {
+	name string
+}
+
+type closed struct{}
+
+type typedAggregate struct {
+	aggregate.TypedRoot[id, *typedAggregate]
+
+	name    string
+	closed  bool
+	eventID string
+}
+
+func init() {
+	aggregate.MustRegister(
+		[]any{opened{}, &renamed{}, closed{}},
+		aggregate.On(func(a *typedAggregate, evt opened) {
+			a.name = evt.name
+		}),
+		aggregate.OnEvent(func(a *typedAggregate, evt *renamed, extra aggregate.Event) {
+			a.name = evt.name
+			a.eventID = extra.ID
+		}),
+		aggregate.On(func(a *typedAggregate, _ closed) {
+			a.closed = true
+		}),
+	)
+}
+
+func TestTypedRootShouldDispatchToRegisteredHandlers(t *testing.T) {
+	var a typedAggregate
+
+	a.Rehydrate(&a)
+
+	a.Apply(opened{name: "john"})
+	a.ApplyWithID("event-id", &renamed{name: "max"})
+	a.Apply(closed{})
+
+	assert.Equal(t, "max", a.name)
+	assert.Equal(t, "event-id", a.eventID)
+	assert.True(t, a.closed)
+	assert.Len(t, a.Events(), 3)
+	assert.Equal(t, a.Events()[0].ID, a.FirstEventCorrelationID())
+}
+
+func TestTypedRootShouldBeRehydratedByStore(t *testing.T) {
+	es := eventStore{
+		storedEvents: []eventstore.StoredEvent{
+			{Event: opened{name: "john"}, ID: "event-1"},
+			{Event: &renamed{name: "max"}, ID: "event-2"},
+		},
+	}
+
+	store := aggregate.NewStore[*typedAggregate](&es)
+
+	var a typedAggregate
+
+	err := store.ByID(context.Background(), "id", &a)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "max", a.name)
+	assert.Equal(t, 2, a.Version())
+	assert.Equal(t, "event-2", a.LastEventID())
+}
+
+type Created struct {
+	Name string
+}
+
+type Renamed struct {
+	Name string
+}
+
+type persistedAggregate struct {
+	aggregate.TypedRoot[id, *persistedAggregate]
+
+	name string
+}
+
+func init() {
+	aggregate.MustRegister(
+		[]any{Created{}, Renamed{}},
+		aggregate.On(func(a *persistedAggregate, evt Created) {
+			a.name = evt.Name
+		}),
+		aggregate.On(func(a *persistedAggregate, evt *Renamed) {
+			a.name = evt.Name
+		}),
+	)
+}
+
+func TestTypedRootShouldRoundTripPointerEventsThroughEventStore(t *testing.T) {
+	es, err := eventstore.New(
+		eventstore.NewJSONEncoder(Created{}, Renamed{}),
+		eventstore.WithSQLiteDB(filepath.Join(t.TempDir(), "typed.db")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = es.Close()
+	})
+
+	store := aggregate.NewStore[*persistedAggregate](es)
+
+	ctx := context.Background()
+
+	var a persistedAggregate
+
+	a.Rehydrate(&a)
+	a.ID = "id"
+	a.Apply(Created{Name: "john"}, &Renamed{Name: "max"})
+
+	assert.NoError(t, store.Save(ctx, &a))
+
+	var loaded persistedAggregate
+
+	assert.NoError(t, store.ByID(ctx, "id", &loaded))
+	assert.Equal(t, "max", loaded.name)
+	assert.Equal(t, 2, loaded.Version())
+}
+
+func TestRegisterShouldReportMissingHandlers(t *testing.T) {
+	type other struct {
+		aggregate.TypedRoot[id, *other]
+	}
+
+	err := aggregate.Register(
+		[]any{opened{}, closed{}, &renamed{}},
+		aggregate.On(func(_ *other, _ opened) {}),
+	)
+
+	assert.ErrorIs(t, err, aggregate.ErrMissingAggregateEventHandler)
+	assert.ErrorContains(t, err, "aggregate_test.closed")
+	assert.ErrorContains(t, err, "*aggregate_test.renamed")
+}
+
+func TestTypedRootShouldPanicIfNotRegistered(t *testing.T) {
+	type unregistered struct {
+		aggregate.TypedRoot[id, *unregistered]
+	}
+
+	var a unregistered
+
+	assert.PanicsWithError(t, "aggregate event handlers not registered: *aggregate_test.unregistered", func() {
+		a.Rehydrate(&a)
+	})
+}
+
+func TestTypedRootShouldPanicOnApplyWithNoRehydrate(t *testing.T) {
+	var a typedAggregate
+
+	assert.PanicsWithError(t, aggregate.ErrAggregateRootNotRehydrated.Error(), func() {
+		a.Apply(closed{})
+	})
+}
+
+type withdrawn struct {
+	amount int
+}
+
+type typedAccount struct {
+	aggregate.TypedRoot[id, *typedAccount]
+
+	balance int
+}
+
+var errInsufficientFunds = errors.New("insufficient funds")
+
+func init() {
+	aggregate.MustRegister(
+		[]any{opened{}, withdrawn{}},
+		aggregate.On(func(a *typedAccount, _ opened) {
+			a.balance = 10
+		}),
+		aggregate.TryOn(func(a *typedAccount, evt withdrawn) error {
+			if evt.amount > a.balance {
+				return errInsufficientFunds
+			}
+
+			a.balance -= evt.amount
+
+			return nil
+		}),
+	)
+}
+
+func TestTypedRootTryApplyShouldReturnHandlerError(t *testing.T) {
+	var a typedAccount
+
+	assert.NoError(t, a.TryRehydrate(&a))
+
+	err := a.TryApply(opened{}, withdrawn{amount: 4}, withdrawn{amount: 7})
+
+	assert.ErrorIs(t, err, errInsufficientFunds)
+	assert.Equal(t, 6, a.balance)
+	assert.Len(t, a.Events(), 2)
+}
+
+func TestTypedRootApplyShouldIgnoreHandlerErrors(t *testing.T) {
+	var a typedAccount
+
+	a.Rehydrate(&a)
+	a.Apply(opened{}, withdrawn{amount: 11}, withdrawn{amount: 4})
+
+	assert.Equal(t, 6, a.balance)
+	assert.Len(t, a.Events(), 3)
+}
+
+func TestTypedRootTryApplyShouldReturnErrors(t *testing.T) {
+	type unregistered struct {
+		aggregate.TypedRoot[id, *unregistered]
+	}
+
+	var u unregistered
+
+	assert.ErrorIs(t, u.TryRehydrate(&u), aggregate.ErrAggregateNotRegistered)
+
+	var a typedAccount
+
+	assert.ErrorIs(t, a.TryApply(opened{}), aggregate.ErrAggregateRootNotRehydrated)
+	assert.ErrorIs(t, a.TryApplyWithID("id", opened{}), aggregate.ErrAggregateRootNotRehydrated)
+	assert.ErrorIs(t, a.TryRehydrate(a), aggregate.ErrAggregateRootNotAPointer)
+
+	assert.NoError(t, a.TryRehydrate(&a))
+	assert.ErrorIs(t, a.TryApply(closed{}), aggregate.ErrMissingAggregateEventHandler)
+}
+
+func TestStoreShouldReturnRehydrationError(t *testing.T) {
+	es := eventStore{
+		storedEvents: []eventstore.StoredEvent{
+			{Event: opened{}, ID: "event-1"},
+			{Event: withdrawn{amount: 20}, ID: "event-2"},
+		},
+	}
+
+	store := aggregate.NewStore[*typedAccount](&es)
+
+	var a typedAccount
+
+	err := store.ByID(context.Background(), "id", &a)
+
+	var rerr *aggregate.RehydrationError
+
+	assert.ErrorAs(t, err, &rerr)
+	assert.ErrorIs(t, err, errInsufficientFunds)
+	assert.Equal(t, "event-2", rerr.EventID)
+	assert.Equal(t, 2, rerr.Version)
+}
@@ -0,0 +1,109 @@
+package aggregate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aneshas/tx/v2"
+)
+
+// NewUnitOfWork constructs a new unit of work which commits aggregates in
+// transactions started using the transactor (eg. tx.New(gormtx.NewDB(eventStore.DB)))
+func NewUnitOfWork(t tx.Transactor) *UnitOfWork {
+	return &UnitOfWork{
+		transactor: t,
+	}
+}
+
+// UnitOfWork tracks aggregates loaded (see Load) or created (see Track) using
+// stores of different aggregate types and saves all of them atomically (see Commit).
+// The stores need to take part in the transaction passed via context (EventStore does)
+type UnitOfWork struct {
+	transactor tx.Transactor
+
+	mu      sync.Mutex
+	tracked []tracked
+}
+
+type tracked struct {
+	root   Rooter
+	append func(ctx context.Context) error
+}
+
+// Load finds the aggregate by its stream id using the store (see Store.ByID) and
+// tracks it in the unit of work
+func Load[T Rooter](ctx context.Context, uow *UnitOfWork, store *Store[T], id string, root T) error {
+	err := store.ByID(ctx, id, root)
+	if err != nil {
+		return err
+	}
+
+	Track(uow, store, root)
+
+	return nil
+}
+
+// Track tracks the aggregate (eg. a newly created one) in the unit of work, so it is
+// saved using the store on Commit. Tracking the same aggregate more than once has no effect
+func Track[T Rooter](uow *UnitOfWork, store *Store[T], root T) {
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+
+	for _, t := range uow.tracked {
+		if t.root == Rooter(root) {
+			return
+		}
+	}
+
+	uow.tracked = append(uow.tracked, tracked{
+		root: root,
+		append: func(ctx context.Context) error {
+			return store.append(ctx, root)
+		},
+	})
+}
+
+// Commit saves the events of all tracked aggregates which have uncommitted events
+// in a single transaction (in the order the aggregates were tracked in).
+// Each aggregate stream is checked for concurrent modifications separately and
+// if saving any of them fails the transaction is rolled back, so none are saved.
+// Aggregates are marked as committed (see Store.Save) only if the transaction
+// is committed. The unit of work stops tracking all aggregates either way
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var dirty []tracked
+
+	for _, t := range u.tracked {
+		if len(t.root.Events()) > 0 {
+			dirty = append(dirty, t)
+		}
+	}
+
+	u.tracked = nil
+
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	err := u.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		for _, t := range dirty {
+			if err := t.append(ctx); err != nil {
+				return fmt.Errorf("saving aggregate %s: %w", t.root.StringID(), err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range dirty {
+		markCommitted(t.root)
+	}
+
+	return nil
+}
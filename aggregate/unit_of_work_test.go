@@ -0,0 +1,190 @@
+package aggregate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/aggregate"
+	"github.com/aneshas/tx/v2"
+	"github.com/aneshas/tx/v2/gormtx"
+	"github.com/stretchr/testify/assert"
+)
+
+func unitOfWorkStores(t *testing.T) (*eventstore.EventStore, *aggregate.Store[*foo], *aggregate.Store[*counter], *aggregate.UnitOfWork) {
+	t.Helper()
+
+	es, err := eventstore.New(
+		eventstore.NewJSONEncoder(fooEvent{}, incremented{}),
+		eventstore.WithSQLiteDB(filepath.Join(t.TempDir(), "uow.db")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = es.Close()
+	})
+
+	uow := aggregate.NewUnitOfWork(tx.New(gormtx.NewDB(es.DB)))
+
+	return es, aggregate.NewStore[*foo](es), aggregate.NewStore[*counter](es), uow
+}
+
+func TestUnitOfWorkShouldSaveAllAggregates(t *testing.T) {
+	es, fooStore, counterStore, uow := unitOfWorkStores(t)
+
+	ctx := context.Background()
+
+	var f foo
+
+	f.Rehydrate(&f)
+	f.doMoreStuff()
+
+	aggregate.Track(uow, fooStore, &f)
+
+	var c counter
+
+	c.ID = "counter-1"
+	c.Rehydrate(&c)
+	c.Apply(incremented{}, incremented{})
+
+	aggregate.Track(uow, counterStore, &c)
+
+	err := uow.Commit(ctx)
+
+	assert.NoError(t, err)
+
+	evts, err := es.ReadStream(ctx, "foo-1")
+
+	assert.NoError(t, err)
+	assert.Len(t, evts, 1)
+
+	evts, err = es.ReadStream(ctx, "counter-1")
+
+	assert.NoError(t, err)
+	assert.Len(t, evts, 2)
+
+	assert.Empty(t, f.Events())
+	assert.Equal(t, 1, f.Version())
+	assert.Empty(t, c.Events())
+	assert.Equal(t, 2, c.Version())
+}
+
+func TestUnitOfWorkShouldSaveLoadedAggregatesOnce(t *testing.T) {
+	es, _, counterStore, uow := unitOfWorkStores(t)
+
+	ctx := context.Background()
+
+	err := es.AppendStream(ctx, "counter-1", 0, []eventstore.EventToStore{{Event: incremented{}}})
+
+	assert.NoError(t, err)
+
+	c := counter{}
+	c.ID = "counter-1"
+
+	err = aggregate.Load(ctx, uow, counterStore, "counter-1", &c)
+
+	assert.NoError(t, err)
+
+	c.Apply(incremented{})
+
+	aggregate.Track(uow, counterStore, &c)
+
+	var untouched counter
+
+	untouched.ID = "counter-2"
+	untouched.Rehydrate(&untouched)
+
+	aggregate.Track(uow, counterStore, &untouched)
+
+	err = uow.Commit(ctx)
+
+	assert.NoError(t, err)
+
+	evts, err := es.ReadStream(ctx, "counter-1")
+
+	assert.NoError(t, err)
+	assert.Len(t, evts, 2)
+	assert.Equal(t, 2, c.n)
+	assert.Equal(t, 2, c.Version())
+
+	_, err = es.ReadStream(ctx, "counter-2")
+
+	assert.ErrorIs(t, err, eventstore.ErrStreamNotFound)
+}
+
+func TestUnitOfWorkShouldRollBackAllAggregatesOnFailure(t *testing.T) {
+	es, fooStore, counterStore, uow := unitOfWorkStores(t)
+
+	ctx := context.Background()
+
+	err := es.AppendStream(ctx, "counter-1", 0, []eventstore.EventToStore{{Event: incremented{}}})
+
+	assert.NoError(t, err)
+
+	var f foo
+
+	f.Rehydrate(&f)
+	f.doMoreStuff()
+
+	aggregate.Track(uow, fooStore, &f)
+
+	var stale counter
+
+	stale.ID = "counter-1"
+	stale.Rehydrate(&stale)
+	stale.Apply(incremented{})
+
+	aggregate.Track(uow, counterStore, &stale)
+
+	err = uow.Commit(ctx)
+
+	assert.ErrorIs(t, err, eventstore.ErrConcurrencyCheckFailed)
+	assert.ErrorContains(t, err, "counter-1")
+
+	_, err = es.ReadStream(ctx, "foo-1")
+
+	assert.ErrorIs(t, err, eventstore.ErrStreamNotFound)
+
+	assert.Len(t, f.Events(), 1)
+	assert.Equal(t, 0, f.Version())
+	assert.Len(t, stale.Events(), 1)
+}
+
+func TestStoreShouldNotMarkAggregateCommittedWithinTransaction(t *testing.T) {
+	es, fooStore, _, _ := unitOfWorkStores(t)
+
+	ctx := context.Background()
+
+	var f foo
+
+	f.Rehydrate(&f)
+	f.doMoreStuff()
+
+	wantErr := errors.New("rolled back")
+
+	err := tx.New(gormtx.NewDB(es.DB)).WithTransaction(ctx, func(ctx context.Context) error {
+		if err := fooStore.Save(ctx, &f); err != nil {
+			return err
+		}
+
+		return wantErr
+	})
+
+	assert.ErrorIs(t, err, wantErr)
+	assert.Len(t, f.Events(), 1)
+	assert.Equal(t, 0, f.Version())
+
+	_, err = es.ReadStream(ctx, "foo-1")
+
+	assert.ErrorIs(t, err, eventstore.ErrStreamNotFound)
+
+	err = fooStore.Save(ctx, &f)
+
+	assert.NoError(t, err)
+	assert.Empty(t, f.Events())
+	assert.Equal(t, 1, f.Version())
+}
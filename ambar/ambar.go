@@ -1,9 +1,11 @@
 package ambar
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/internal/tracing"
 	"github.com/relvacode/iso8601"
 	"net/http"
 	"time"
@@ -130,18 +132,39 @@ func (a *Ambar) Project(r *http.Request, projection Projection, data []byte) err
 		}
 	}
 
-	return projection(
-		r,
-		eventstore.StoredEvent{
-			Event:              decoded,
-			ID:                 event.Payload.ID,
-			Meta:               meta,
-			Sequence:           event.Payload.Sequence,
-			Type:               event.Payload.Type,
-			CausationEventID:   event.Payload.CausationEventID,
-			CorrelationEventID: event.Payload.CorrelationEventID,
-			StreamID:           event.Payload.StreamID,
-			StreamVersion:      event.Payload.StreamVersion,
-			OccurredOn:         occurredOn,
-		})
+	evt := eventstore.StoredEvent{
+		Event: decoded,
+		Raw: eventstore.EncodedEvt{
+			Data: event.Payload.Event,
+			Type: event.Payload.Type,
+		},
+		ID:                 event.Payload.ID,
+		Meta:               meta,
+		Sequence:           event.Payload.Sequence,
+		Type:               event.Payload.Type,
+		CausationEventID:   event.Payload.CausationEventID,
+		CorrelationEventID: event.Payload.CorrelationEventID,
+		StreamID:           event.Payload.StreamID,
+		StreamVersion:      event.Payload.StreamVersion,
+		OccurredOn:         occurredOn,
+	}
+
+	ctx := context.Background()
+
+	if r != nil {
+		ctx = r.Context()
+	}
+
+	ctx, span := eventstore.StartEventSpan(
+		ctx,
+		tracing.Tracer(),
+		"ambar.Project",
+		evt,
+	)
+
+	if r != nil {
+		r = r.WithContext(ctx)
+	}
+
+	return tracing.EndSpan(span, projection(r, evt))
 }
@@ -21,7 +21,11 @@ func TestShould_Project_Required_Data(t *testing.T) {
 
 	projection := func(_ *http.Request, data eventstore.StoredEvent) error {
 		assert.Equal(t, eventstore.StoredEvent{
-			Event:              testutil.Event,
+			Event: testutil.Event,
+			Raw: eventstore.EncodedEvt{
+				Data: testutil.AmbarPayload.Event,
+				Type: "TestEvent",
+			},
 			Meta:               nil,
 			ID:                 testutil.AmbarPayload.ID,
 			Sequence:           testutil.AmbarPayload.Sequence,
@@ -0,0 +1,59 @@
+package eventstore
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// CheckpointStore persists the position (sequence) up to which a named
+// projection has processed the event store.
+// This package offers EventStore as CheckpointStore implementation
+type CheckpointStore interface {
+	Checkpoint(ctx context.Context, name string) (uint64, error)
+	SaveCheckpoint(ctx context.Context, name string, seq uint64) error
+}
+
+type gormCheckpoint struct {
+	Name      string `gorm:"primaryKey"`
+	Sequence  uint64
+	UpdatedAt time.Time
+}
+
+// TableName returns gorm table name
+func (gc *gormCheckpoint) TableName() string { return "projection_checkpoint" }
+
+// Checkpoint returns the last sequence a named projection has processed
+// or 0 if the projection has not stored a checkpoint yet
+func (es *EventStore) Checkpoint(ctx context.Context, name string) (uint64, error) {
+	var cps []gormCheckpoint
+
+	err := es.conn(ctx).
+		Where("name = ?", name).
+		Limit(1).
+		Find(&cps).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if len(cps) == 0 {
+		return 0, nil
+	}
+
+	return cps[0].Sequence, nil
+}
+
+// SaveCheckpoint stores the last sequence a named projection has processed
+func (es *EventStore) SaveCheckpoint(ctx context.Context, name string, seq uint64) error {
+	return es.conn(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"sequence", "updated_at"}),
+		}).
+		Create(&gormCheckpoint{
+			Name:      name,
+			Sequence:  seq,
+			UpdatedAt: time.Now().UTC(),
+		}).Error
+}
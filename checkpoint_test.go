@@ -0,0 +1,30 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldReadSavedCheckpoint(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	ctx := context.Background()
+
+	seq, err := es.Checkpoint(ctx, "some-projection")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), seq)
+
+	assert.NoError(t, es.SaveCheckpoint(ctx, "some-projection", 5))
+	assert.NoError(t, es.SaveCheckpoint(ctx, "some-projection", 10))
+	assert.NoError(t, es.SaveCheckpoint(ctx, "another-projection", 3))
+
+	seq, err = es.Checkpoint(ctx, "some-projection")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), seq)
+}
@@ -0,0 +1,39 @@
+package eventstore
+
+// DecodePolicy specifies how events of types not registered
+// with the encoder (see ErrEventNotRegistered) are handled when read
+type DecodePolicy int
+
+const (
+	// DecodeFail fails reading with ErrEventNotRegistered (default)
+	DecodeFail DecodePolicy = iota
+
+	// DecodeSkip skips events of unregistered types
+	// (named projections checkpoint past them once caught up)
+	DecodeSkip
+
+	// DecodeRaw delivers events of unregistered types with
+	// StoredEvent.Event set to the raw EncodedEvt
+	DecodeRaw
+)
+
+// WithDecodePolicy is an event store option that specifies how events of
+// types not registered with the encoder are handled by all reads
+// (can be overridden per subscription using WithSubDecodePolicy)
+func WithDecodePolicy(p DecodePolicy) Option {
+	return func(cfg Cfg) Cfg {
+		cfg.DecodePolicy = p
+
+		return cfg
+	}
+}
+
+// WithSubDecodePolicy is a subscription / read all option that specifies how
+// events of types not registered with the encoder are handled (see DecodePolicy)
+func WithSubDecodePolicy(p DecodePolicy) SubAllOpt {
+	return func(cfg SubAllConfig) SubAllConfig {
+		cfg.decodePolicy = p
+
+		return cfg
+	}
+}
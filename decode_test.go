@@ -0,0 +1,225 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+type UnknownEvent struct {
+	Foo string
+}
+
+func appendUnknown(t *testing.T, es *eventstore.EventStore) {
+	t.Helper()
+
+	err := es.AppendStream(
+		context.Background(),
+		"stream",
+		eventstore.InitialStreamVersion,
+		toEventToStore(SomeEvent{UserID: "user-1"}, UnknownEvent{Foo: "bar"}, SomeEvent{UserID: "user-2"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadFailsOnUnregisteredEventsByDefault(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUnknown(t, es)
+
+	_, err := es.ReadAll(context.Background())
+
+	assert.ErrorIs(t, err, eventstore.ErrEventNotRegistered)
+
+	_, err = es.ReadStream(context.Background(), "stream")
+
+	assert.ErrorIs(t, err, eventstore.ErrEventNotRegistered)
+}
+
+func TestReadSkipsUnregisteredEvents(t *testing.T) {
+	es, cleanup := eventStoreWithDec(
+		t,
+		eventstore.NewJSONEncoder(SomeEvent{}),
+		eventstore.WithDecodePolicy(eventstore.DecodeSkip),
+	)
+
+	defer cleanup()
+
+	appendUnknown(t, es)
+
+	evts, err := es.ReadStream(context.Background(), "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, evts, 2)
+
+	sub, err := es.SubscribeAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sub.Close()
+
+	evts = readAllSub(t, sub, 2)
+
+	assert.Equal(t, SomeEvent{UserID: "user-2"}, evts[1].Event)
+}
+
+func TestSubscriptionCanOverrideDecodePolicy(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUnknown(t, es)
+
+	evts, err := es.ReadAll(context.Background(), eventstore.WithSubDecodePolicy(eventstore.DecodeRaw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, evts, 3)
+	assert.Equal(t, eventstore.EncodedEvt{Type: "UnknownEvent", Data: `{"Foo":"bar"}`}, evts[1].Event)
+}
+
+func TestSubscriptionGroupAcksSkippedEvents(t *testing.T) {
+	es, cleanup := eventStoreWithDec(
+		t,
+		eventstore.NewJSONEncoder(SomeEvent{}),
+		eventstore.WithDecodePolicy(eventstore.DecodeSkip),
+	)
+
+	defer cleanup()
+
+	appendUnknown(t, es)
+
+	g, err := es.SubscriptionGroup(context.Background(), "group", eventstore.WithAckTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evts, err := g.Fetch(context.Background(), "consumer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, evts, 2)
+
+	for _, evt := range evts {
+		assert.NoError(t, g.Ack(context.Background(), "consumer", evt.Sequence))
+	}
+
+	evts, err = g.Fetch(context.Background(), "consumer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Empty(t, evts)
+}
+
+func TestProjectorCheckpointAdvancesPastSkippedEvents(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []eventstore.ProjectionOpt
+	}{
+		{name: "sequential"},
+		{name: "partitioned", opts: []eventstore.ProjectionOpt{eventstore.WithPartitions(2)}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			es, cleanup := eventStoreWithDec(
+				t,
+				eventstore.NewJSONEncoder(SomeEvent{}),
+				eventstore.WithDecodePolicy(eventstore.DecodeSkip),
+			)
+
+			defer cleanup()
+
+			appendUnknown(t, es)
+
+			err := es.AppendStream(context.Background(), "stream", 3, toEventToStore(UnknownEvent{Foo: "baz"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+
+			defer cancel()
+
+			p := eventstore.NewProjector(es)
+
+			p.AddNamed("users", func(_ context.Context, _ eventstore.StoredEvent) error { return nil }, tc.opts...)
+
+			go func() { _ = p.Run(ctx) }()
+
+			assert.Eventually(t, func() bool {
+				seq, err := es.Checkpoint(ctx, "users")
+
+				return err == nil && seq == 4
+			}, 2*time.Second, 50*time.Millisecond)
+		})
+	}
+}
+
+func TestSubscriptionGroupKeepsParkedSkippedEvents(t *testing.T) {
+	if *withPG {
+		t.Skip("opens the shared sqlite database")
+	}
+
+	es, cleanup := eventStoreWithDec(t, eventstore.NewJSONEncoder(SomeEvent{}, UnknownEvent{}))
+
+	defer cleanup()
+
+	appendUnknown(t, es)
+
+	ctx := context.Background()
+
+	g, err := es.SubscriptionGroup(ctx, "group", eventstore.WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evts, err := g.Fetch(ctx, "consumer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, evt := range evts {
+		assert.NoError(t, g.Nack(ctx, "consumer", evt.Sequence))
+	}
+
+	// Same database, UnknownEvent is no longer registered
+	skipping, err := eventstore.New(
+		eventstore.NewJSONEncoder(SomeEvent{}),
+		eventstore.WithSQLiteDB("file::memory:?cache=shared"),
+		eventstore.WithDecodePolicy(eventstore.DecodeSkip),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = skipping.Close() }()
+
+	sg, err := skipping.SubscriptionGroup(ctx, "group", eventstore.WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parked, err := sg.Parked(ctx)
+
+	assert.NoError(t, err)
+	assert.Len(t, parked, 2)
+
+	parked, err = g.Parked(ctx)
+
+	assert.NoError(t, err)
+	assert.Len(t, parked, 3)
+}
@@ -1,6 +1,9 @@
 package eventstore
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // EventToStore represents an event that is to be stored in the event store
 type EventToStore struct {
@@ -16,8 +19,13 @@ type EventToStore struct {
 
 // StoredEvent holds stored event data and meta data
 type StoredEvent struct {
+	// Event is the decoded event (nil if the event was read using WithLazyDecoding)
 	Event any
-	Meta  map[string]string
+
+	// Raw is the encoded event as stored in the event store
+	Raw EncodedEvt
+
+	Meta map[string]string
 
 	ID                 string
 	Sequence           uint64
@@ -27,4 +35,27 @@ type StoredEvent struct {
 	StreamID           string
 	StreamVersion      int
 	OccurredOn         time.Time
+
+	lazy *lazyEvent
+}
+
+// Decode returns the decoded event. Events read using WithLazyDecoding are
+// decoded on the first call and the result is cached, otherwise Event is returned
+func (e StoredEvent) Decode() (any, error) {
+	if e.lazy == nil {
+		return e.Event, nil
+	}
+
+	e.lazy.once.Do(func() {
+		e.lazy.evt, e.lazy.err = e.lazy.decode()
+	})
+
+	return e.lazy.evt, e.lazy.err
+}
+
+type lazyEvent struct {
+	once   sync.Once
+	decode func() (any, error)
+	evt    any
+	err    error
 }
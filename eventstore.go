@@ -10,10 +10,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync/atomic"
 	"time"
 
+	"github.com/aneshas/eventstore/internal/tracing"
 	"github.com/aneshas/tx/v2/gormtx"
 	uuid2 "github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/sqlite"
 
 	"gorm.io/driver/postgres"
@@ -83,15 +86,28 @@ func New(enc Encoder, opts ...Option) (*EventStore, error) {
 	}
 
 	return &EventStore{
-		DB:  db,
-		enc: enc,
-	}, db.AutoMigrate(&gormEvent{})
+		DB:           db,
+		enc:          enc,
+		decodePolicy: cfg.DecodePolicy,
+		metrics:      cfg.Metrics,
+		traceContext: cfg.TraceContext,
+	}, db.AutoMigrate(
+		&gormEvent{},
+		&gormCheckpoint{},
+		&gormLease{},
+		&gormGroup{},
+		&gormGroupEvent{},
+		&gormQuarantinedEvent{},
+	)
 }
 
 // Cfg represents event store configuration
 type Cfg struct {
-	PostgresDSN string
-	SQLitePath  string
+	PostgresDSN  string
+	SQLitePath   string
+	DecodePolicy DecodePolicy
+	Metrics      Metrics
+	TraceContext bool
 }
 
 // Option represents event store configuration option
@@ -119,8 +135,11 @@ func WithSQLiteDB(path string) Option {
 
 // EventStore represents a sql based event store implementation
 type EventStore struct {
-	DB  *gorm.DB
-	enc Encoder
+	DB           *gorm.DB
+	enc          Encoder
+	decodePolicy DecodePolicy
+	metrics      Metrics
+	traceContext bool
 }
 
 // Close should be called as a part of cleanup process
@@ -179,24 +198,52 @@ func (es *EventStore) AppendStream(
 	expectedVer int,
 	events []EventToStore) error {
 
+	ctx, span := tracing.Tracer().Start(
+		ctx,
+		"eventstore.AppendStream",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			AttrStream.String(stream),
+			AttrVersion.Int(expectedVer),
+			AttrEventCount.Int(len(events)),
+		),
+	)
+
+	start := time.Now()
+
+	size, err := es.appendStream(ctx, stream, expectedVer, events)
+
+	es.observer().Appended(stream, len(events), size, time.Since(start), err)
+
+	return tracing.EndSpan(span, err)
+}
+
+func (es *EventStore) appendStream(
+	ctx context.Context,
+	stream string,
+	expectedVer int,
+	events []EventToStore) (int, error) {
+
 	if len(stream) == 0 {
-		return fmt.Errorf("stream name must be provided")
+		return 0, fmt.Errorf("stream name must be provided")
 	}
 
 	if expectedVer < InitialStreamVersion {
-		return fmt.Errorf("expected version cannot be less than 0")
+		return 0, fmt.Errorf("expected version cannot be less than 0")
 	}
 
 	if len(events) == 0 {
-		return nil
+		return 0, nil
 	}
 
+	var size int
+
 	eventsToSave := make([]gormEvent, len(events))
 
 	for i, evt := range events {
 		encoded, err := es.enc.Encode(evt.Event)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		expectedVer++
@@ -218,10 +265,16 @@ func (es *EventStore) AppendStream(
 			event.CausationEventID = &evt.CausationEventID
 		}
 
-		if evt.Meta != nil {
-			m, err := json.Marshal(evt.Meta)
+		meta := evt.Meta
+
+		if es.traceContext {
+			meta = InjectTraceContext(ctx, meta)
+		}
+
+		if meta != nil {
+			m, err := json.Marshal(meta)
 			if err != nil {
-				return err
+				return 0, err
 			}
 
 			ms := string(m)
@@ -232,14 +285,23 @@ func (es *EventStore) AppendStream(
 		if event.ID == "" {
 			uuid, err := uuid2.NewV7()
 			if err != nil {
-				return err
+				return 0, err
 			}
 
 			event.ID = uuid.String()
 		}
 
-		if !event.OccurredOn.IsZero() {
-			event.OccurredOn = time.Now().UTC()
+		if event.OccurredOn.IsZero() {
+			event.OccurredOn = time.Now()
+		}
+
+		// Stored in UTC, so occurrence times are comparable (see FromTime)
+		event.OccurredOn = event.OccurredOn.UTC()
+
+		size += len(event.Data)
+
+		if event.Meta != nil {
+			size += len(*event.Meta)
 		}
 
 		eventsToSave[i] = event
@@ -248,10 +310,10 @@ func (es *EventStore) AppendStream(
 	tx := es.conn(ctx).Create(&eventsToSave)
 
 	if errors.Is(tx.Error, gorm.ErrDuplicatedKey) {
-		return ErrConcurrencyCheckFailed
+		return size, ErrConcurrencyCheckFailed
 	}
 
-	return tx.Error
+	return size, tx.Error
 }
 
 func (es *EventStore) conn(ctx context.Context) *gorm.DB {
@@ -268,6 +330,13 @@ type SubAllConfig struct {
 	offset       int
 	batchSize    int
 	pollInterval time.Duration
+	types        []string
+	caughtUp     func()
+	start        func(db *gorm.DB) (int, error)
+	decodePolicy DecodePolicy
+	poison       func(context.Context, *PoisonEventError) error
+	quarantine   bool
+	lazy         bool
 }
 
 // SubAllOpt represents subscribe to all events option
@@ -275,9 +344,11 @@ type SubAllOpt func(SubAllConfig) SubAllConfig
 
 // WithOffset is a subscription / read all option that indicates an offset in
 // the event store from which to start reading events (exclusive)
+// It overrides any previously provided start position (see FromStart)
 func WithOffset(offset int) SubAllOpt {
 	return func(cfg SubAllConfig) SubAllConfig {
 		cfg.offset = offset
+		cfg.start = nil
 
 		return cfg
 	}
@@ -303,6 +374,47 @@ func WithPollInterval(d time.Duration) SubAllOpt {
 	}
 }
 
+// WithEventTypes is a subscription/read all option that filters events
+// by their (encoded) type, so only events of the given types are read
+func WithEventTypes(types ...string) SubAllOpt {
+	return func(cfg SubAllConfig) SubAllConfig {
+		cfg.types = types
+
+		return cfg
+	}
+}
+
+// WithCaughtUp is a subscription option that specifies a function called each time
+// the subscription catches up with the event store (there are no more events to read).
+// It is an explicit alternative to the io.EOF convention of Subscription.Err
+func WithCaughtUp(fn func()) SubAllOpt {
+	return func(cfg SubAllConfig) SubAllConfig {
+		cfg.caughtUp = fn
+
+		return cfg
+	}
+}
+
+func (es *EventStore) subAllConfig(opts []SubAllOpt) (SubAllConfig, error) {
+	cfg := SubAllConfig{
+		offset:       0,
+		batchSize:    100,
+		pollInterval: 100 * time.Millisecond,
+		caughtUp:     func() {},
+		decodePolicy: es.decodePolicy,
+	}
+
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+
+	if cfg.batchSize < 1 {
+		return SubAllConfig{}, fmt.Errorf("batch size should be at least 1")
+	}
+
+	return cfg, nil
+}
+
 // Subscription represents ReadAll subscription that is used for streaming
 // incoming events
 type Subscription struct {
@@ -312,10 +424,15 @@ type Subscription struct {
 	// the subscription itself will continue polling the event store for new events
 	// each time we empty the Err channel. This means that reading from Err (in
 	// case of io.EOF) can be strategically used in order to achieve backpressure
+	// (see SubscribeAllSeq and WithCaughtUp for an iterator based alternative)
 	Err       chan error
 	EventData chan StoredEvent
 
 	close chan struct{}
+
+	// read is the sequence up to which events have been read and either
+	// sent to EventData or skipped (see DecodeSkip and WithPoisonHandler)
+	read *atomic.Uint64
 }
 
 // Close closes the subscription and halts the polling of sqldb
@@ -327,56 +444,59 @@ func (s Subscription) Close() {
 	s.close <- struct{}{}
 }
 
-// ReadAll will read all events from the event store by internally creating a
-// a subscription and depleting it until io.EOF is encountered
-// WARNING: Use with caution as this method will read the entire event store
-// in a blocking fashion (probably best used in combination with offset option)
-func (es *EventStore) ReadAll(ctx context.Context, opts ...SubAllOpt) ([]StoredEvent, error) {
-	sub, err := es.SubscribeAll(ctx, opts...)
-	if err != nil {
-		return nil, err
+// skipped returns the sequence up to which the events have been skipped
+// while reading, if all events sent to EventData have been received
+// (so a caught up consumer can advance past the skipped events)
+func (s Subscription) skipped() (uint64, bool) {
+	if s.read == nil {
+		return 0, false
 	}
 
-	defer sub.Close()
+	// Loaded before checking EventData, since events are sent before read is stored
+	read := s.read.Load()
 
-	var events []StoredEvent
+	return read, len(s.EventData) == 0
+}
 
-	for {
-		select {
-		case data := <-sub.EventData:
-			events = append(events, data)
+// ReadAll will read all events from the event store (see ReadAllSeq)
+// WARNING: Use with caution as this method will read the entire event store
+// in a blocking fashion (probably best used in combination with offset option)
+func (es *EventStore) ReadAll(ctx context.Context, opts ...SubAllOpt) ([]StoredEvent, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "eventstore.ReadAll")
 
-		case err := <-sub.Err:
-			if errors.Is(err, io.EOF) {
-				return events, nil
-			}
+	var events []StoredEvent
 
-			return nil, err
+	for evt, err := range es.ReadAllSeq(ctx, opts...) {
+		if err != nil {
+			return nil, tracing.EndSpan(span, err)
 		}
+
+		events = append(events, evt)
 	}
+
+	span.SetAttributes(AttrEventCount.Int(len(events)))
+
+	return events, tracing.EndSpan(span, nil)
 }
 
 // SubscribeAll will create a subscription which can be used to stream all events in an
 // orderly fashion. This mechanism should probably be mostly useful for building projections
 func (es *EventStore) SubscribeAll(ctx context.Context, opts ...SubAllOpt) (Subscription, error) {
-	cfg := SubAllConfig{
-		offset:       0,
-		batchSize:    100,
-		pollInterval: 100 * time.Millisecond,
-	}
-
-	for _, opt := range opts {
-		cfg = opt(cfg)
+	cfg, err := es.subAllConfig(opts)
+	if err != nil {
+		return Subscription{}, err
 	}
 
-	if cfg.batchSize < 1 {
-		return Subscription{}, fmt.Errorf("batch size should be at least 1")
+	cfg, err = es.resolveStart(es.DB.WithContext(ctx), cfg)
+	if err != nil {
+		return Subscription{}, err
 	}
 
 	sub := Subscription{
 		Err:       make(chan error, 1),
 		EventData: make(chan StoredEvent, cfg.batchSize),
 		close:     make(chan struct{}, 1),
+		read:      new(atomic.Uint64),
 	}
 
 	go func() {
@@ -404,35 +524,71 @@ func (es *EventStore) SubscribeAll(ctx context.Context, opts ...SubAllOpt) (Subs
 					return
 				}
 
-				var evts []gormEvent
+				var (
+					evts []gormEvent
+					head uint64
+				)
+
+				if len(cfg.types) > 0 {
+					// Read before the filtered query, so the subscription can be moved past
+					// events of other types once there are no more matching events to read
+					seq, _, err := es.Head(ctx)
+					if err != nil {
+						done = err
 
-				if err := es.DB.
-					Where("sequence > ?", cfg.offset).
-					Order("sequence asc").
-					Limit(cfg.batchSize).
-					Find(&evts).Error; err != nil {
+						break
+					}
+
+					head = seq
+				}
+
+				polled := time.Now()
+
+				err := es.allQuery(es.DB, cfg).Find(&evts).Error
+
+				es.observer().Polled(len(evts), time.Since(polled), err)
+
+				if err != nil {
 					done = err
 
 					break
 				}
 
+				if len(evts) != 0 {
+					tracePoll(ctx, polled, cfg.offset, len(evts))
+				}
+
 				if len(evts) == 0 {
+					if head > uint64(cfg.offset) {
+						cfg.offset = int(head)
+
+						sub.read.Store(head)
+					}
+
+					cfg.caughtUp()
+
 					sub.Err <- io.EOF
 
 					break
 				}
 
-				cfg.offset = cfg.offset + len(evts)
+				cfg.offset = int(evts[len(evts)-1].Sequence)
 
-				decoded, err := es.decodeEvents(evts)
-				if err != nil {
-					done = err
+				for _, evt := range evts {
+					decoded, ok, err := es.decodeSub(ctx, evt, cfg)
+					if err != nil {
+						done = err
 
-					break
+						break
+					}
+
+					if ok {
+						sub.EventData <- decoded
+					}
 				}
 
-				for _, evt := range decoded {
-					sub.EventData <- evt
+				if done == nil {
+					sub.read.Store(uint64(cfg.offset))
 				}
 			}
 		}
@@ -442,64 +598,147 @@ func (es *EventStore) SubscribeAll(ctx context.Context, opts ...SubAllOpt) (Subs
 }
 
 // ReadStream will read all events associated with provided stream
-// If there are no events stored for a given stream ErrStreamNotFound will be returned
+// If there are no events stored for a given stream ErrStreamNotFound will be returned.
+// Like AppendStream, ReadStream reads within the transaction passed in ctx (if any),
+// so the events appended within the transaction are read as well
 func (es *EventStore) ReadStream(ctx context.Context, stream string) ([]StoredEvent, error) {
-	var events []gormEvent
+	ctx, span := tracing.Tracer().Start(
+		ctx,
+		"eventstore.ReadStream",
+		trace.WithAttributes(AttrStream.String(stream)),
+	)
 
-	if len(stream) == 0 {
-		return nil, fmt.Errorf("stream name must be provided")
+	start := time.Now()
+
+	var events []StoredEvent
+
+	for evt, err := range es.ReadStreamSeq(ctx, stream) {
+		if err != nil {
+			es.observer().StreamRead(stream, 0, time.Since(start), err)
+
+			return nil, tracing.EndSpan(span, err)
+		}
+
+		events = append(events, evt)
 	}
 
+	es.observer().StreamRead(stream, len(events), time.Since(start), nil)
+
+	span.SetAttributes(AttrEventCount.Int(len(events)))
+
+	return events, tracing.EndSpan(span, nil)
+}
+
+// Head returns the sequence and the occurrence time of the latest event
+// in the event store (zero values are returned if the event store is empty)
+func (es *EventStore) Head(ctx context.Context) (uint64, time.Time, error) {
+	var evt gormEvent
+
 	if err := es.DB.
 		WithContext(ctx).
-		Where("stream_id = ?", stream).
-		Order("sequence asc").
-		Find(&events).Error; err != nil {
+		Order("sequence desc").
+		Limit(1).
+		Find(&evt).Error; err != nil {
 
-		return nil, err
+		return 0, time.Time{}, err
 	}
 
-	if len(events) == 0 {
-		return nil, ErrStreamNotFound
-	}
-
-	return es.decodeEvents(events)
+	return evt.Sequence, evt.OccurredOn, nil
 }
 
-func (es *EventStore) decodeEvents(events []gormEvent) ([]StoredEvent, error) {
-	out := make([]StoredEvent, len(events))
+func (es *EventStore) decodeEvents(events []gormEvent, policy DecodePolicy) ([]StoredEvent, error) {
+	out := make([]StoredEvent, 0, len(events))
 
-	for i, evt := range events {
-		data, err := es.enc.Decode(&EncodedEvt{
-			Data: evt.Data,
-			Type: evt.Type,
-		})
+	for _, evt := range events {
+		decoded, ok, err := es.decodeEvent(evt, policy)
 		if err != nil {
 			return nil, err
 		}
 
-		var meta map[string]string
+		if ok {
+			out = append(out, decoded)
+		}
+	}
 
-		if evt.Meta != nil {
-			err = json.Unmarshal([]byte(*evt.Meta), &meta)
-			if err != nil {
-				return nil, err
-			}
+	return out, nil
+}
+
+// decodeEvent decodes the stored event according to the decode policy
+// and reports whether the event should be delivered
+func (es *EventStore) decodeEvent(evt gormEvent, policy DecodePolicy) (StoredEvent, bool, error) {
+	stored, err := storedEvent(evt)
+	if err != nil {
+		return StoredEvent{}, false, err
+	}
+
+	data, err := es.decodeData(evt)
+	if errors.Is(err, ErrEventNotRegistered) {
+		switch policy {
+		case DecodeSkip:
+			return StoredEvent{}, false, nil
+
+		case DecodeRaw:
+			data, err = stored.Raw, nil
 		}
+	}
+
+	if err != nil {
+		return StoredEvent{}, false, err
+	}
+
+	stored.Event = data
+
+	return stored, true, nil
+}
+
+func (es *EventStore) decodeData(evt gormEvent) (any, error) {
+	data, err := es.enc.Decode(&EncodedEvt{
+		Data: evt.Data,
+		Type: evt.Type,
+	})
+	if errors.Is(err, ErrEventNotRegistered) {
+		// Not a poison event, handled according to the decode policy
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, newPoisonEventError(evt, err)
+	}
+
+	return data, nil
+}
 
-		out[i] = StoredEvent{
-			Event:              data,
-			Meta:               meta,
-			ID:                 evt.ID,
-			Sequence:           evt.Sequence,
-			Type:               evt.Type,
-			CausationEventID:   evt.CausationEventID,
-			CorrelationEventID: evt.CorrelationEventID,
-			StreamID:           evt.StreamID,
-			StreamVersion:      evt.StreamVersion,
-			OccurredOn:         evt.OccurredOn,
+// storedEvent converts the stored event leaving the event data undecoded
+func storedEvent(evt gormEvent) (StoredEvent, error) {
+	var meta map[string]string
+
+	if evt.Meta != nil {
+		err := json.Unmarshal([]byte(*evt.Meta), &meta)
+		if err != nil {
+			return StoredEvent{}, newPoisonEventError(evt, err)
 		}
 	}
 
-	return out, nil
+	stored := rawEvent(evt)
+	stored.Meta = meta
+
+	return stored, nil
+}
+
+// rawEvent converts the stored event leaving the event data and meta undecoded
+func rawEvent(evt gormEvent) StoredEvent {
+	return StoredEvent{
+		Raw: EncodedEvt{
+			Data: evt.Data,
+			Type: evt.Type,
+		},
+		ID:                 evt.ID,
+		Sequence:           evt.Sequence,
+		Type:               evt.Type,
+		CausationEventID:   evt.CausationEventID,
+		CorrelationEventID: evt.CorrelationEventID,
+		StreamID:           evt.StreamID,
+		StreamVersion:      evt.StreamVersion,
+		OccurredOn:         evt.OccurredOn,
+	}
 }
@@ -606,7 +606,7 @@ func eventStore(t *testing.T) (*eventstore.EventStore, func()) {
 	return eventStoreWithDec(t, eventstore.NewJSONEncoder(SomeEvent{}))
 }
 
-func eventStoreWithDec(t *testing.T, enc eventstore.Encoder) (*eventstore.EventStore, func()) {
+func eventStoreWithDec(t *testing.T, enc eventstore.Encoder, opts ...eventstore.Option) (*eventstore.EventStore, func()) {
 	t.Helper()
 
 	if *withPG {
@@ -636,7 +636,7 @@ func eventStoreWithDec(t *testing.T, enc eventstore.Encoder) (*eventstore.EventS
 			t.Fatal(err)
 		}
 
-		es, err := eventstore.New(enc, eventstore.WithPostgresDB(dsn))
+		es, err := eventstore.New(enc, append(opts, eventstore.WithPostgresDB(dsn))...)
 		if err != nil {
 			t.Fatalf("error creating es: %v", err)
 		}
@@ -649,7 +649,7 @@ func eventStoreWithDec(t *testing.T, enc eventstore.Encoder) (*eventstore.EventS
 		}
 	}
 
-	es, err := eventstore.New(enc, eventstore.WithSQLiteDB("file::memory:?cache=shared"))
+	es, err := eventstore.New(enc, append(opts, eventstore.WithSQLiteDB("file::memory:?cache=shared"))...)
 	if err != nil {
 		t.Fatalf("error creating es: %v", err)
 	}
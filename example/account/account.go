@@ -1,25 +1,26 @@
 package account
 
 import (
-	"fmt"
+	"errors"
 	"github.com/aneshas/eventstore/aggregate"
 )
 
+// ErrInsufficientFunds is returned when withdrawing more than the account balance
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
 // New opens a new Account
 func New(id ID, holder string) (*Account, error) {
 	var acc Account
 
 	// We always need to call Rehydrate on a fresh instance in order to initialize the aggregate
 	// so the events can be applied to it properly
-	// (aggregate.Store ByID will do this automatically for us when we load an aggregate from the event store)
+	// (aggregate.Store ByID and aggregate.Executor will do this automatically for us)
 	acc.Rehydrate(&acc)
 
-	acc.Apply(
-		NewAccountOpened{
-			AccountID: id.String(),
-			Holder:    holder,
-		},
-	)
+	err := acc.Open(id, holder)
+	if err != nil {
+		return nil, err
+	}
 
 	return &acc, nil
 }
@@ -31,6 +32,23 @@ type Account struct {
 	Balance int
 }
 
+// Reset clears the account state (see aggregate.WithRetry)
+func (a *Account) Reset() {
+	a.Balance = 0
+}
+
+// Open opens the account (eg. a fresh instance passed to an executor created with aggregate.WithCreate)
+func (a *Account) Open(id ID, holder string) error {
+	a.Apply(
+		NewAccountOpened{
+			AccountID: id.String(),
+			Holder:    holder,
+		},
+	)
+
+	return nil
+}
+
 // Deposit money
 func (a *Account) Deposit(amount int) {
 	a.Apply(
@@ -44,7 +62,7 @@ func (a *Account) Deposit(amount int) {
 // Withdraw money
 func (a *Account) Withdraw(amount int) error {
 	if a.Balance < amount {
-		return fmt.Errorf("insufficient funds")
+		return ErrInsufficientFunds
 	}
 
 	a.Apply(
@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"github.com/aneshas/eventstore"
 	"github.com/aneshas/eventstore-example/account"
 	"github.com/aneshas/eventstore/aggregate"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 )
 
 var pg = flag.Bool("pg", false, "Run with postgres db (set env DSN to pg connection string)")
@@ -43,8 +46,15 @@ func main() {
 	e.GET("/accounts/:id/withdraw/:amount", NewWithdrawFromAccountHandlerFunc(eventStore))
 
 	e.HTTPErrorHandler = func(err error, c echo.Context) {
-		if errors.Is(err, aggregate.ErrAggregateNotFound) {
+		switch {
+		case errors.Is(err, aggregate.ErrAggregateNotFound):
 			_ = c.String(http.StatusNotFound, "Account not found")
+
+		case errors.Is(err, account.ErrInsufficientFunds):
+			_ = c.String(http.StatusBadRequest, err.Error())
+
+		default:
+			e.DefaultHTTPErrorHandler(err, c)
 		}
 	}
 
@@ -75,15 +85,17 @@ type newAccountResp struct {
 
 // NewOpenAccountHandlerFunc creates new account opening endpoint example
 func NewOpenAccountHandlerFunc(eventStore *eventstore.EventStore) echo.HandlerFunc {
-	store := aggregate.NewStore[*account.Account](eventStore)
+	exec := aggregate.NewExecutor(
+		aggregate.NewStore[*account.Account](eventStore),
+		aggregate.WithCreate(),
+	)
 
 	return func(c echo.Context) error {
-		acc, err := account.New(account.NewID(), "John Doe")
-		if err != nil {
-			return err
-		}
+		var acc account.Account
 
-		err = store.Save(c.Request().Context(), acc)
+		err := exec(c.Request().Context(), &acc, func(ctx context.Context) error {
+			return acc.Open(account.NewID(), "John Doe")
+		})
 		if err != nil {
 			return err
 		}
@@ -99,24 +111,25 @@ type depositResp struct {
 
 // NewDepositToAccountHandlerFunc creates new deposit to account endpoint example
 func NewDepositToAccountHandlerFunc(eventStore *eventstore.EventStore) echo.HandlerFunc {
-	store := aggregate.NewStore[*account.Account](eventStore)
+	exec := newExecutor(eventStore)
 
 	return func(c echo.Context) error {
-		var (
-			acc account.Account
-			ctx = c.Request().Context()
-		)
+		var acc account.Account
 
-		err := store.ByID(ctx, c.Param("id"), &acc)
+		id, err := uuid.Parse(c.Param("id"))
 		if err != nil {
-			return err
+			return aggregate.ErrAggregateNotFound
 		}
 
+		acc.ID = account.ID{UUID: id}
+
 		amount, _ := strconv.Atoi(c.Param("amount"))
 
-		acc.Deposit(amount)
+		err = exec(c.Request().Context(), &acc, func(ctx context.Context) error {
+			acc.Deposit(amount)
 
-		err = store.Save(ctx, &acc)
+			return nil
+		})
 		if err != nil {
 			return err
 		}
@@ -138,27 +151,23 @@ type withdrawResp struct {
 
 // NewWithdrawFromAccountHandlerFunc creates new withdraw from account endpoint example
 func NewWithdrawFromAccountHandlerFunc(eventStore *eventstore.EventStore) echo.HandlerFunc {
-	store := aggregate.NewStore[*account.Account](eventStore)
+	exec := newExecutor(eventStore)
 
 	return func(c echo.Context) error {
-		var (
-			acc account.Account
-			ctx = c.Request().Context()
-		)
+		var acc account.Account
 
-		err := store.ByID(ctx, c.Param("id"), &acc)
+		id, err := uuid.Parse(c.Param("id"))
 		if err != nil {
-			return err
+			return aggregate.ErrAggregateNotFound
 		}
 
-		amount, _ := strconv.Atoi(c.Param("amount"))
+		acc.ID = account.ID{UUID: id}
 
-		err = acc.Withdraw(amount)
-		if err != nil {
-			return c.String(http.StatusBadRequest, err.Error())
-		}
+		amount, _ := strconv.Atoi(c.Param("amount"))
 
-		err = store.Save(ctx, &acc)
+		err = exec(c.Request().Context(), &acc, func(ctx context.Context) error {
+			return acc.Withdraw(amount)
+		})
 		if err != nil {
 			return err
 		}
@@ -173,6 +182,19 @@ func NewWithdrawFromAccountHandlerFunc(eventStore *eventstore.EventStore) echo.H
 	}
 }
 
+// newExecutor creates an executor which retries commands against the latest
+// account state on concurrency conflicts
+func newExecutor(eventStore *eventstore.EventStore) aggregate.Executor[*account.Account] {
+	return aggregate.NewExecutor(
+		aggregate.NewStore[*account.Account](eventStore),
+		aggregate.WithRetry(
+			3,
+			aggregate.ExponentialBackoff(10*time.Millisecond, 100*time.Millisecond),
+			(*account.Account).Reset,
+		),
+	)
+}
+
 func checkErr(err error) {
 	if err != nil {
 		log.Fatal(err)
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -25,9 +26,17 @@ func main() {
 
 	p := eventstore.NewProjector(eventStore)
 
-	p.Add(
-		NewConsoleOutputProjection(),
-		NewJSONFileProjection("accounts.json"),
+	console := NewConsoleOutputProjection()
+
+	p.AddNamed("console", console.Project, console.Filter())
+
+	jsonFile, err := NewJSONFileProjection("accounts.json")
+	checkErr(err)
+
+	p.AddNamed(
+		"json_file",
+		jsonFile.Project,
+		eventstore.WithFlush(jsonFile.Flush, 100, 3*time.Second),
 	)
 
 	log.Fatal(p.Run(context.Background()))
@@ -39,60 +48,88 @@ func checkErr(err error) {
 	}
 }
 
-// NewConsoleOutputProjection constructs an example projection that outputs
+// NewConsoleOutputProjection constructs an example typed projection that outputs
 // new accounts to the console. It might as well be to any kind of
 // database, disk, memory etc...
-func NewConsoleOutputProjection() eventstore.Projection {
-	return func(data eventstore.StoredEvent) error {
-		switch data.Event.(type) {
-		case account.NewAccountOpened:
-			evt := data.Event.(account.NewAccountOpened)
-			fmt.Printf("Account: #%s | Holder: <%s>\n", evt.AccountID, evt.Holder)
+func NewConsoleOutputProjection() *eventstore.TypedProjection {
+	p := eventstore.NewTypedProjection()
 
-		default:
-			fmt.Println("not interested in this event")
-		}
+	eventstore.Handle(p, func(_ context.Context, evt account.NewAccountOpened, _ eventstore.StoredEvent) error {
+		fmt.Printf("Account: #%s | Holder: <%s>\n", evt.AccountID, evt.Holder)
 
 		return nil
-	}
+	})
+
+	return p
 }
 
-// NewJSONFileProjection makes use of flush after projection in order to
+// JSONFileProjection makes use of a flushing projection in order to
 // periodically write accounts to a json file on disk
-func NewJSONFileProjection(fName string) eventstore.Projection {
-	var accounts []string
-
-	return eventstore.FlushAfter(
-		func(data eventstore.StoredEvent) error {
-			switch data.Event.(type) {
-			case account.NewAccountOpened:
-				evt := data.Event.(account.NewAccountOpened)
-				accounts = append(accounts, fmt.Sprintf("Account: #%s Holder: %s", evt.AccountID, evt.Holder))
-			default:
-				fmt.Println("not interested in this event")
-			}
-
-			return nil
-		},
-
-		func() error {
-			if len(accounts) == 0 {
-				return nil
-			}
-
-			data, err := json.Marshal(accounts)
-			if err != nil {
-				return err
-			}
-
-			err = os.WriteFile(fName, data, os.ModeAppend|os.ModePerm)
-			if err != nil {
-				return err
-			}
-
-			return nil
-		},
-
-		3*time.Second,
-	)
+type JSONFileProjection struct {
+	fName    string
+	accounts []string
+	pending  []string
+}
+
+// NewJSONFileProjection instantiates JSONFileProjection writing to fName.
+// Accounts already written to fName are loaded, since the projection is named
+// and resumes from its checkpoint instead of replaying all events
+func NewJSONFileProjection(fName string) (*JSONFileProjection, error) {
+	p := JSONFileProjection{fName: fName}
+
+	data, err := os.ReadFile(fName)
+	if errors.Is(err, os.ErrNotExist) {
+		return &p, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &p.accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Project buffers accounts until the next flush
+func (p *JSONFileProjection) Project(_ context.Context, data eventstore.StoredEvent) error {
+	switch evt := data.Event.(type) {
+	case account.NewAccountOpened:
+		p.pending = append(p.pending, fmt.Sprintf("Account: #%s Holder: %s", evt.AccountID, evt.Holder))
+	default:
+		fmt.Println("not interested in this event")
+	}
+
+	return nil
+}
+
+// Flush writes buffered accounts to the json file. Buffered accounts are dropped
+// regardless of the outcome since the projector redelivers them if the flush fails
+func (p *JSONFileProjection) Flush(_ context.Context) error {
+	pending := p.pending
+
+	p.pending = nil
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	accounts := append(p.accounts, pending...)
+
+	data, err := json.Marshal(accounts)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(p.fName, data, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	p.accounts = accounts
+
+	return nil
 }
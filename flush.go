@@ -0,0 +1,27 @@
+package eventstore
+
+import (
+	"context"
+	"time"
+)
+
+// WithFlush is a projection option for projections which buffer their state and
+// periodically write it to the read model (flush). The projector calls flush once size
+// events have been handled since the last flush, or once interval has elapsed since the
+// first of them was handled, whichever comes first. Flush is also called when the
+// projection is stopped (eg. the context passed to Run is canceled), so pending state is
+// not lost on graceful shutdown.
+// The checkpoint of the projection is only stored after a successful flush. If flush
+// fails, the error is handled the same way as projection errors: the projection is
+// restarted and the events handled since the last successful flush are redelivered,
+// thus flush should drop the buffered state even if it fails.
+// Flushing projections cannot be partitioned or transactional
+func WithFlush(flush func(ctx context.Context) error, size int, interval time.Duration) ProjectionOpt {
+	return func(cfg ProjectionCfg) ProjectionCfg {
+		cfg.flush = flush
+		cfg.flushSize = max(size, 1)
+		cfg.flushInterval = interval
+
+		return cfg
+	}
+}
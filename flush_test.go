@@ -0,0 +1,143 @@
+package eventstore_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferedModel struct {
+	m       sync.Mutex
+	buffer  []string
+	flushed [][]string
+	fail    int
+}
+
+func (bm *bufferedModel) project(_ context.Context, evt eventstore.StoredEvent) error {
+	bm.m.Lock()
+	defer bm.m.Unlock()
+
+	bm.buffer = append(bm.buffer, evt.Event.(SomeEvent).UserID)
+
+	return nil
+}
+
+func (bm *bufferedModel) flush(_ context.Context) error {
+	bm.m.Lock()
+	defer bm.m.Unlock()
+
+	buffer := bm.buffer
+
+	bm.buffer = nil
+
+	if bm.fail > 0 {
+		bm.fail--
+
+		return fmt.Errorf("some flush error")
+	}
+
+	bm.flushed = append(bm.flushed, buffer)
+
+	return nil
+}
+
+func (bm *bufferedModel) get() [][]string {
+	bm.m.Lock()
+	defer bm.m.Unlock()
+
+	return append([][]string(nil), bm.flushed...)
+}
+
+func TestShouldFlushProjectionOnSizeAndShutdown(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 5)
+
+	var bm bufferedModel
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed("users", bm.project, eventstore.WithFlush(bm.flush, 2, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+
+	defer cancel()
+
+	assert.NoError(t, p.Run(ctx))
+
+	assert.Equal(t, [][]string{{"user-1", "user-2"}, {"user-3", "user-4"}, {"user-5"}}, bm.get())
+
+	seq, err := es.Checkpoint(context.Background(), "users")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), seq)
+}
+
+func TestShouldFlushProjectionOnInterval(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	var bm bufferedModel
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed("users", bm.project, eventstore.WithFlush(bm.flush, 100, 100*time.Millisecond))
+
+	go func() { _ = p.Run(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([][]string{{"user-1", "user-2", "user-3"}}, bm.get())
+	}, time.Second, 20*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		seq, err := es.Checkpoint(context.Background(), "users")
+
+		return err == nil && seq == 3
+	}, time.Second, 20*time.Millisecond)
+}
+
+func TestShouldRedeliverEventsIfFlushFails(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 3)
+
+	bm := bufferedModel{fail: 1}
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed("users", bm.project, eventstore.WithFlush(bm.flush, 2, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	assert.NoError(t, p.Run(ctx))
+
+	assert.Equal(t, [][]string{{"user-1", "user-2"}, {"user-3"}}, bm.get())
+}
+
+func TestFlushingProjectionCannotBePartitioned(t *testing.T) {
+	var bm bufferedModel
+
+	p := eventstore.NewProjector(streamer{})
+
+	p.AddNamed("users", bm.project, eventstore.WithFlush(bm.flush, 2, time.Hour), eventstore.WithPartitions(2))
+
+	assert.Error(t, p.Run(context.Background()))
+}
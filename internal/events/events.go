@@ -0,0 +1,34 @@
+// Package events provides event type helpers shared by the event store packages
+package events
+
+import "reflect"
+
+// As converts the event to E taking or dereferencing
+// its pointer if E is a pointer to the decoded type or vice versa
+func As[E any](decoded any) (E, bool) {
+	if evt, ok := decoded.(E); ok {
+		return evt, true
+	}
+
+	var zero E
+
+	v := reflect.ValueOf(decoded)
+	if !v.IsValid() {
+		return zero, false
+	}
+
+	t := reflect.TypeFor[E]()
+
+	switch {
+	case t.Kind() == reflect.Pointer && v.Type() == t.Elem():
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+
+		return ptr.Interface().(E), true
+
+	case v.Kind() == reflect.Pointer && v.Type().Elem() == t && !v.IsNil():
+		return v.Elem().Interface().(E), true
+	}
+
+	return zero, false
+}
@@ -0,0 +1,187 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// streamBatchSize is the number of stream events read at once by ReadStreamSeq
+const streamBatchSize = 100
+
+// ReadStreamSeq returns an iterator over all events associated with provided stream.
+// Events are read in batches of streamBatchSize and decoded lazily as they are iterated over.
+// If there are no events stored for a given stream ErrStreamNotFound is yielded.
+// The iteration stops after the first yielded error
+func (es *EventStore) ReadStreamSeq(ctx context.Context, stream string) iter.Seq2[StoredEvent, error] {
+	return func(yield func(StoredEvent, error) bool) {
+		if len(stream) == 0 {
+			yield(StoredEvent{}, fmt.Errorf("stream name must be provided"))
+
+			return
+		}
+
+		cfg := SubAllConfig{
+			batchSize:    streamBatchSize,
+			decodePolicy: es.decodePolicy,
+		}
+
+		var read int
+
+		for {
+			q := es.conn(ctx).
+				Model(&gormEvent{}).
+				Where("stream_id = ? AND sequence > ?", stream, cfg.offset).
+				Order("sequence asc").
+				Limit(cfg.batchSize)
+
+			n, last, ok := es.scan(ctx, q, cfg, yield)
+			if !ok {
+				return
+			}
+
+			read += n
+
+			if n < cfg.batchSize {
+				break
+			}
+
+			cfg.offset = int(last)
+		}
+
+		if read == 0 {
+			yield(StoredEvent{}, ErrStreamNotFound)
+		}
+	}
+}
+
+// ReadAllSeq returns an iterator over all events in the event store (up to the moment
+// the last batch is read). Events are read in batches (see WithBatchSize), each
+// batch being decoded lazily as it is iterated over.
+// The iteration stops after the first yielded error
+func (es *EventStore) ReadAllSeq(ctx context.Context, opts ...SubAllOpt) iter.Seq2[StoredEvent, error] {
+	return func(yield func(StoredEvent, error) bool) {
+		cfg, err := es.subAllConfig(opts)
+		if err == nil {
+			cfg, err = es.resolveStart(es.conn(ctx), cfg)
+		}
+
+		if err != nil {
+			yield(StoredEvent{}, err)
+
+			return
+		}
+
+		for {
+			n, last, ok := es.scan(ctx, es.allQuery(es.conn(ctx), cfg), cfg, yield)
+			if !ok || n < cfg.batchSize {
+				return
+			}
+
+			cfg.offset = int(last)
+		}
+	}
+}
+
+// SubscribeAllSeq returns an iterator over all events in the event store which,
+// unlike ReadAllSeq, keeps polling the event store for new events once it has
+// caught up (see WithCaughtUp and WithPollInterval) until ctx is done,
+// in which case ctx.Err() is yielded.
+// The iteration stops after the first yielded error
+func (es *EventStore) SubscribeAllSeq(ctx context.Context, opts ...SubAllOpt) iter.Seq2[StoredEvent, error] {
+	return func(yield func(StoredEvent, error) bool) {
+		cfg, err := es.subAllConfig(opts)
+		if err == nil {
+			cfg, err = es.resolveStart(es.conn(ctx), cfg)
+		}
+
+		if err != nil {
+			yield(StoredEvent{}, err)
+
+			return
+		}
+
+		for {
+			n, last, ok := es.scan(ctx, es.allQuery(es.conn(ctx), cfg), cfg, yield)
+			if !ok {
+				return
+			}
+
+			if n != 0 {
+				cfg.offset = int(last)
+
+				continue
+			}
+
+			cfg.caughtUp()
+
+			select {
+			case <-ctx.Done():
+				yield(StoredEvent{}, ctx.Err())
+
+				return
+
+			case <-time.After(cfg.pollInterval):
+			}
+		}
+	}
+}
+
+// allQuery builds the query reading the next batch of events after the configured offset
+func (es *EventStore) allQuery(db *gorm.DB, cfg SubAllConfig) *gorm.DB {
+	q := db.Model(&gormEvent{}).Where("sequence > ?", cfg.offset)
+
+	if len(cfg.types) > 0 {
+		q = q.Where("type IN ?", cfg.types)
+	}
+
+	return q.
+		Order("sequence asc").
+		Limit(cfg.batchSize)
+}
+
+// scan reads the batch of events selected by q and passes them to yield, returning
+// the number of read events, the sequence of the last one and whether the iteration
+// should continue. The batch is read before yielding, so the connection (or transaction)
+// is not busy while the events are handled (eg. events can be appended meanwhile)
+func (es *EventStore) scan(ctx context.Context, q *gorm.DB, cfg SubAllConfig, yield func(StoredEvent, error) bool) (int, uint64, bool) {
+	var evts []gormEvent
+
+	polled := time.Now()
+
+	err := q.Find(&evts).Error
+
+	es.observer().Polled(len(evts), time.Since(polled), err)
+
+	if err != nil {
+		yield(StoredEvent{}, err)
+
+		return 0, 0, false
+	}
+
+	if len(evts) != 0 {
+		tracePoll(ctx, polled, cfg.offset, len(evts))
+	}
+
+	var last uint64
+
+	for i, evt := range evts {
+		decoded, ok, err := es.decodeSub(ctx, evt, cfg)
+		if err != nil {
+			yield(StoredEvent{}, err)
+
+			return i, last, false
+		}
+
+		if ok && !yield(decoded, nil) {
+			return i, last, false
+		}
+
+		last = evt.Sequence
+	}
+
+	return len(evts), last, true
+}
@@ -0,0 +1,291 @@
+package eventstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/tx/v2"
+	"github.com/aneshas/tx/v2/gormtx"
+	"github.com/stretchr/testify/assert"
+)
+
+func userIDs(t *testing.T, seq func(func(eventstore.StoredEvent, error) bool)) []string {
+	t.Helper()
+
+	var ids []string
+
+	for evt, err := range seq {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, evt.Event.(SomeEvent).UserID)
+	}
+
+	return ids
+}
+
+func TestReadStreamSeqShouldStreamEvents(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 3)
+
+	ids := userIDs(t, es.ReadStreamSeq(context.Background(), "stream"))
+
+	assert.Equal(t, []string{"user-1", "user-2", "user-3"}, ids)
+}
+
+func TestReadStreamSeqShouldYieldNotFound(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	for _, err := range es.ReadStreamSeq(context.Background(), "stream") {
+		if !errors.Is(err, eventstore.ErrStreamNotFound) {
+			t.Fatal("stream should not have been found")
+		}
+	}
+}
+
+func TestReadAllSeqShouldReadAllBatches(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 5)
+
+	ids := userIDs(t, es.ReadAllSeq(
+		context.Background(),
+		eventstore.WithOffset(1),
+		eventstore.WithBatchSize(2),
+	))
+
+	assert.Equal(t, []string{"user-2", "user-3", "user-4", "user-5"}, ids)
+}
+
+func TestReadAllSeqShouldStopOnBreak(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 5)
+
+	var n int
+
+	for _, err := range es.ReadAllSeq(context.Background(), eventstore.WithBatchSize(2)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n++
+
+		if n == 3 {
+			break
+		}
+	}
+
+	assert.Equal(t, 3, n)
+}
+
+func TestSubscribeAllSeqShouldNotifyCaughtUpAndStreamNewEvents(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+	defer cancel()
+
+	var (
+		ids      []string
+		caughtUp int
+		lastErr  error
+	)
+
+	seq := es.SubscribeAllSeq(
+		ctx,
+		eventstore.WithPollInterval(10*time.Millisecond),
+		eventstore.WithCaughtUp(func() {
+			caughtUp++
+
+			if caughtUp == 1 {
+				err := es.AppendStream(context.Background(), "other-stream", eventstore.InitialStreamVersion, toEventToStore(SomeEvent{UserID: "user-3"}))
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}),
+	)
+
+	for evt, err := range seq {
+		if err != nil {
+			lastErr = err
+
+			break
+		}
+
+		ids = append(ids, evt.Event.(SomeEvent).UserID)
+
+		if len(ids) == 3 {
+			cancel()
+		}
+	}
+
+	assert.Equal(t, []string{"user-1", "user-2", "user-3"}, ids)
+	assert.GreaterOrEqual(t, caughtUp, 1)
+	assert.ErrorIs(t, lastErr, context.Canceled)
+}
+
+func TestSubscribeAllCallsCaughtUp(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	caughtUp := make(chan struct{}, 1)
+
+	sub, err := es.SubscribeAll(
+		context.Background(),
+		eventstore.WithPollInterval(10*time.Millisecond),
+		eventstore.WithCaughtUp(func() {
+			select {
+			case caughtUp <- struct{}{}:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sub.Close()
+
+	<-sub.Err
+
+	select {
+	case <-caughtUp:
+	case <-time.After(time.Second):
+		t.Fatal("caught up should have been called")
+	}
+}
+
+func TestReadAllSeqShouldAllowWritesWithinTransactionWhileIterating(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 3)
+
+	transactor := tx.New(gormtx.NewDB(es.DB))
+
+	err := transactor.WithTransaction(context.Background(), func(ctx context.Context) error {
+		for evt, err := range es.ReadAllSeq(ctx, eventstore.WithBatchSize(2)) {
+			if err != nil {
+				return err
+			}
+
+			err = es.AppendStream(
+				ctx, "copy-"+evt.StreamID, evt.StreamVersion-1,
+				toEventToStore(SomeEvent{UserID: evt.Event.(SomeEvent).UserID}),
+			)
+			if err != nil {
+				return err
+			}
+
+			if evt.Sequence == 3 {
+				break
+			}
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+
+	ids := userIDs(t, es.ReadStreamSeq(context.Background(), "copy-stream"))
+
+	assert.Equal(t, []string{"user-1", "user-2", "user-3"}, ids)
+}
+
+func TestReadAllSeqShouldAllowWritesWhileIterating(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+	defer cancel()
+
+	for evt, err := range es.ReadAllSeq(ctx, eventstore.WithBatchSize(2)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if evt.Sequence > 3 {
+			break
+		}
+
+		err = es.AppendStream(
+			ctx, "copy-"+evt.StreamID, evt.StreamVersion-1,
+			toEventToStore(SomeEvent{UserID: evt.Event.(SomeEvent).UserID}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := userIDs(t, es.ReadStreamSeq(context.Background(), "copy-stream"))
+
+	assert.Equal(t, []string{"user-1", "user-2", "user-3"}, ids)
+}
+
+func TestReadStreamShouldReadWithinTransaction(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	transactor := tx.New(gormtx.NewDB(es.DB))
+
+	err := transactor.WithTransaction(context.Background(), func(ctx context.Context) error {
+		err := es.AppendStream(ctx, "stream", eventstore.InitialStreamVersion, toEventToStore(SomeEvent{UserID: "user-1"}))
+		if err != nil {
+			return err
+		}
+
+		evts, err := es.ReadStream(ctx, "stream")
+		if err != nil {
+			return err
+		}
+
+		assert.Len(t, evts, 1)
+
+		return errors.New("rollback")
+	})
+
+	assert.Error(t, err)
+
+	_, err = es.ReadStream(context.Background(), "stream")
+
+	assert.ErrorIs(t, err, eventstore.ErrStreamNotFound)
+}
+
+func TestReadStreamSeqShouldReadAllBatches(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 250)
+
+	ids := userIDs(t, es.ReadStreamSeq(context.Background(), "stream"))
+
+	assert.Len(t, ids, 250)
+	assert.Equal(t, "user-250", ids[249])
+}
@@ -6,14 +6,15 @@ import (
 )
 
 // NewJSONEncoder constructs json encoder
-// It receives a slice of event types it should be able to encode/decode
+// It receives a slice of event types it should be able to encode/decode.
+// Pointer events are stored and decoded as the type they point to
 func NewJSONEncoder(events ...any) *JsonEncoder {
 	enc := JsonEncoder{
 		types: make(map[string]reflect.Type),
 	}
 
 	for _, evt := range events {
-		t := reflect.TypeOf(evt)
+		t := eventType(evt)
 		enc.types[t.Name()] = t
 	}
 
@@ -34,7 +35,7 @@ func (e *JsonEncoder) Encode(evt any) (*EncodedEvt, error) {
 	}
 
 	return &EncodedEvt{
-		Type: reflect.TypeOf(evt).Name(),
+		Type: eventType(evt).Name(),
 		Data: string(data),
 	}, nil
 }
@@ -55,3 +56,12 @@ func (e *JsonEncoder) Decode(evt *EncodedEvt) (any, error) {
 
 	return v.Elem().Interface(), nil
 }
+
+func eventType(evt any) reflect.Type {
+	t := reflect.TypeOf(evt)
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+
+	return t
+}
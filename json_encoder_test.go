@@ -62,3 +62,25 @@ func TestShouldErrorOutIfEvtTypeUnknown(t *testing.T) {
 		t.Fatal("should error out")
 	}
 }
+
+func TestShouldEncodePointerEventsByTypeName(t *testing.T) {
+	enc := eventstore.NewJSONEncoder(&SomeEvent{})
+
+	encoded, err := enc.Encode(&SomeEvent{UserID: "some-user"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if encoded.Type != "SomeEvent" {
+		t.Fatalf("pointer event should be stored by type name. got: %q", encoded.Type)
+	}
+
+	decoded, err := enc.Decode(encoded)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if !reflect.DeepEqual(SomeEvent{UserID: "some-user"}, decoded) {
+		t.Fatalf("event not decoded. got: %#v", decoded)
+	}
+}
@@ -0,0 +1,33 @@
+package eventstore
+
+// WithLazyDecoding is a subscription / read all option that defers decoding
+// of the event data until StoredEvent.Decode is called, so consumers which
+// ignore or forward events (see StoredEvent.Raw) do not pay for decoding them.
+// Since events are not decoded while reading, the decode policy does not apply
+// and decoding errors (see PoisonEventError) are returned by StoredEvent.Decode
+func WithLazyDecoding() SubAllOpt {
+	return func(cfg SubAllConfig) SubAllConfig {
+		cfg.lazy = true
+
+		return cfg
+	}
+}
+
+func (es *EventStore) decodeLazy(evt gormEvent, cfg SubAllConfig) (StoredEvent, bool, error) {
+	if !cfg.lazy {
+		return es.decodeEvent(evt, cfg.decodePolicy)
+	}
+
+	stored, err := storedEvent(evt)
+	if err != nil {
+		return StoredEvent{}, false, err
+	}
+
+	stored.lazy = &lazyEvent{
+		decode: func() (any, error) {
+			return es.decodeData(evt)
+		},
+	}
+
+	return stored, true, nil
+}
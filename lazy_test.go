@@ -0,0 +1,91 @@
+package eventstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoredEventExposesRawEvent(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 1)
+
+	evts, err := es.ReadStream(context.Background(), "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, eventstore.EncodedEvt{Type: "SomeEvent", Data: `{"UserID":"user-1"}`}, evts[0].Raw)
+
+	decoded, err := evts[0].Decode()
+
+	assert.NoError(t, err)
+	assert.Equal(t, evts[0].Event, decoded)
+}
+
+func TestLazyDecodingDecodesOnDemandOnce(t *testing.T) {
+	var decoded int
+
+	json := eventstore.NewJSONEncoder(SomeEvent{})
+
+	e := enc{
+		encode: json.Encode,
+		decode: func(evt *eventstore.EncodedEvt) (any, error) {
+			decoded++
+
+			return json.Decode(evt)
+		},
+	}
+
+	es, cleanup := eventStoreWithDec(t, e)
+
+	defer cleanup()
+
+	appendUsers(t, es, 2)
+
+	evts, err := es.ReadAll(context.Background(), eventstore.WithLazyDecoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 0, decoded)
+	assert.Nil(t, evts[0].Event)
+
+	for range 2 {
+		evt, err := evts[1].Decode()
+
+		assert.NoError(t, err)
+		assert.Equal(t, SomeEvent{UserID: "user-2"}, evt)
+	}
+
+	assert.Equal(t, 1, decoded)
+}
+
+func TestLazyDecodingReturnsPoisonEventErrorOnDecode(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendPoisoned(t, es)
+
+	evts, err := es.ReadAll(context.Background(), eventstore.WithLazyDecoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = evts[1].Decode()
+
+	var perr *eventstore.PoisonEventError
+
+	if !errors.As(err, &perr) {
+		t.Fatalf("poison event error expected, got %v", err)
+	}
+
+	assert.Equal(t, uint64(2), perr.Sequence)
+}
@@ -0,0 +1,284 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	uuid2 "github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+var errLeaseLost = errors.New("lease lost")
+
+// leaseRetryInterval is the interval at which an instance that does not
+// hold the lease tries to acquire it again
+const leaseRetryInterval = time.Second
+
+// Locker is used by the projector in order to make sure that each named projection
+// is run by a single projector instance at a time (see WithLocker).
+// This package offers EventStore.Locker as Locker implementation
+type Locker interface {
+	// Acquire blocks until the lease with the given name is acquired or ctx is done
+	Acquire(ctx context.Context, name string) (Lease, error)
+}
+
+// Lease represents an acquired lease
+type Lease interface {
+	// Lost is closed once the lease has been lost (eg. the connection to the database was lost)
+	Lost() <-chan struct{}
+
+	// Release releases the lease so it can be acquired by another instance
+	Release(ctx context.Context) error
+}
+
+// WithLocker is a projector option that makes the projector acquire a lease for each
+// named projection before running it. This way the projection is run by a single
+// projector instance even if multiple instances are running. If the instance holding
+// the lease dies, the lease is acquired by one of the remaining instances
+func WithLocker(l Locker) ProjectorOpt {
+	return func(cfg ProjectorCfg) ProjectorCfg {
+		cfg.locker = l
+
+		return cfg
+	}
+}
+
+// Locker returns a Locker backed by the event store database.
+// With postgres, session level advisory locks are used, which are released as soon
+// as the connection of the instance holding the lock is closed.
+// Otherwise, leases are stored in a lease table and have to be renewed by the instance
+// holding them. If not renewed within ttl, the lease can be acquired by another instance.
+// The connection (postgres) or lease (otherwise) is checked every ttl/3, so ttl must be positive
+func (es *EventStore) Locker(ttl time.Duration) (Locker, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("lease ttl must be positive")
+	}
+
+	if es.DB.Dialector.Name() == "postgres" {
+		return &advisoryLocker{es: es, interval: ttl / 3}, nil
+	}
+
+	return &tableLocker{es: es, ttl: ttl}, nil
+}
+
+type lease struct {
+	lost    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+	release func(context.Context) error
+}
+
+func newLease(release func(context.Context) error) *lease {
+	return &lease{
+		lost:    make(chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		release: release,
+	}
+}
+
+// keepAlive calls renew each interval until the lease is released or renew fails
+func (l *lease) keepAlive(interval time.Duration, renew func() error) {
+	defer close(l.stopped)
+
+	for {
+		select {
+		case <-l.stop:
+			return
+
+		case <-time.After(interval):
+			if err := renew(); err != nil {
+				close(l.lost)
+
+				return
+			}
+		}
+	}
+}
+
+// Lost is closed once the lease has been lost
+func (l *lease) Lost() <-chan struct{} { return l.lost }
+
+// Release releases the lease
+func (l *lease) Release(ctx context.Context) error {
+	var err error
+
+	l.once.Do(func() {
+		close(l.stop)
+		<-l.stopped
+
+		err = l.release(ctx)
+	})
+
+	return err
+}
+
+type advisoryLocker struct {
+	es       *EventStore
+	interval time.Duration
+}
+
+// Acquire acquires postgres advisory lock for the name
+func (al *advisoryLocker) Acquire(ctx context.Context, name string) (Lease, error) {
+	sqlDB, err := al.es.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	h := fnv.New64a()
+
+	_, _ = h.Write([]byte(name))
+
+	key := int64(h.Sum64())
+
+	for {
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var acquired bool
+
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired)
+		if err != nil {
+			_ = conn.Close()
+
+			return nil, err
+		}
+
+		if acquired {
+			return al.lease(conn, key), nil
+		}
+
+		_ = conn.Close()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-time.After(leaseRetryInterval):
+		}
+	}
+}
+
+func (al *advisoryLocker) lease(conn *sql.Conn, key int64) Lease {
+	l := newLease(func(ctx context.Context) error {
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key)
+
+		return err
+	})
+
+	go l.keepAlive(al.interval, func() error {
+		_, err := conn.ExecContext(context.Background(), "SELECT 1")
+
+		return err
+	})
+
+	return l
+}
+
+type gormLease struct {
+	Name      string `gorm:"primaryKey"`
+	Owner     string
+	ExpiresAt time.Time
+}
+
+// TableName returns gorm table name
+func (gl *gormLease) TableName() string { return "projection_lease" }
+
+type tableLocker struct {
+	es  *EventStore
+	ttl time.Duration
+}
+
+// Acquire acquires the lease by either inserting it into the lease table
+// or taking over an expired lease
+func (tl *tableLocker) Acquire(ctx context.Context, name string) (Lease, error) {
+	owner := uuid2.NewString()
+
+	for {
+		acquired, err := tl.tryAcquire(ctx, name, owner)
+		if err != nil {
+			return nil, err
+		}
+
+		if acquired {
+			return tl.lease(name, owner), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-time.After(leaseRetryInterval):
+		}
+	}
+}
+
+func (tl *tableLocker) tryAcquire(ctx context.Context, name, owner string) (bool, error) {
+	now := time.Now().UTC()
+
+	tx := tl.es.DB.
+		WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&gormLease{
+			Name:      name,
+			Owner:     owner,
+			ExpiresAt: now.Add(tl.ttl),
+		})
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+
+	if tx.RowsAffected == 1 {
+		return true, nil
+	}
+
+	tx = tl.es.DB.
+		WithContext(ctx).
+		Model(&gormLease{}).
+		Where("name = ? AND expires_at < ?", name, now).
+		Updates(map[string]any{
+			"owner":      owner,
+			"expires_at": now.Add(tl.ttl),
+		})
+
+	return tx.RowsAffected == 1, tx.Error
+}
+
+func (tl *tableLocker) lease(name, owner string) Lease {
+	l := newLease(func(ctx context.Context) error {
+		return tl.es.DB.
+			WithContext(ctx).
+			Where("name = ? AND owner = ?", name, owner).
+			Delete(&gormLease{}).Error
+	})
+
+	go l.keepAlive(tl.ttl/3, func() error {
+		tx := tl.es.DB.
+			Model(&gormLease{}).
+			Where("name = ? AND owner = ?", name, owner).
+			Update("expires_at", time.Now().UTC().Add(tl.ttl))
+		if tx.Error != nil {
+			return tx.Error
+		}
+
+		if tx.RowsAffected == 0 {
+			return errLeaseLost
+		}
+
+		return nil
+	})
+
+	return l
+}
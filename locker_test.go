@@ -0,0 +1,185 @@
+package eventstore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLocker(t *testing.T, es *eventstore.EventStore, ttl time.Duration) eventstore.Locker {
+	l, err := es.Locker(ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l
+}
+
+func TestLockerShouldGrantLeaseToSingleInstance(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	locker := newLocker(t, es, time.Second)
+
+	lease, err := locker.Acquire(context.Background(), "projection")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+
+	defer cancel()
+
+	_, err = locker.Acquire(ctx, "projection")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.NoError(t, lease.Release(context.Background()))
+
+	another, err := locker.Acquire(context.Background(), "projection")
+
+	assert.NoError(t, err)
+	assert.NoError(t, another.Release(context.Background()))
+}
+
+func TestLockerShouldTakeOverExpiredLease(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	err := es.DB.Exec(
+		"INSERT INTO projection_lease (name, owner, expires_at) VALUES (?, ?, ?)",
+		"projection", "dead-instance", time.Now().UTC().Add(-time.Second),
+	).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+
+	defer cancel()
+
+	lease, err := newLocker(t, es, time.Second).Acquire(ctx, "projection")
+
+	assert.NoError(t, err)
+	assert.NoError(t, lease.Release(context.Background()))
+}
+
+func TestLeaseShouldBeLostIfTakenOver(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	lease, err := newLocker(t, es, 300*time.Millisecond).Acquire(context.Background(), "projection")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = es.DB.Exec("UPDATE projection_lease SET owner = ?", "another-instance").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease should have been lost")
+	}
+}
+
+func TestShouldRunNamedProjectionOnSingleProjectorInstance(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	err := es.AppendStream(
+		context.Background(), "stream", eventstore.InitialStreamVersion,
+		toEventToStore(SomeEvent{UserID: "user-1"}, SomeEvent{UserID: "user-2"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		m   sync.Mutex
+		got = make(map[int][]string)
+	)
+
+	locker := newLocker(t, es, time.Second)
+
+	run := func(ctx context.Context, instance int) {
+		p := eventstore.NewProjector(es, eventstore.WithLocker(locker))
+
+		p.AddNamed("users", func(_ context.Context, evt eventstore.StoredEvent) error {
+			m.Lock()
+			defer m.Unlock()
+
+			got[instance] = append(got[instance], evt.Event.(SomeEvent).UserID)
+
+			return nil
+		})
+
+		_ = p.Run(ctx)
+	}
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+
+	defer stopSecond()
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		run(firstCtx, 1)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+
+	go run(secondCtx, 2)
+
+	time.Sleep(500 * time.Millisecond)
+
+	stopFirst()
+	wg.Wait()
+
+	err = es.AppendStream(context.Background(), "stream", 2, toEventToStore(SomeEvent{UserID: "user-3"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Eventually(t, func() bool {
+		m.Lock()
+		defer m.Unlock()
+
+		return len(got[2]) == 1
+	}, 3*time.Second, 50*time.Millisecond)
+
+	m.Lock()
+	defer m.Unlock()
+
+	assert.Equal(t, []string{"user-1", "user-2"}, got[1])
+	assert.Equal(t, []string{"user-3"}, got[2])
+}
+
+func TestLockerTTLMustBePositive(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	_, err := es.Locker(0)
+
+	assert.Error(t, err)
+
+	_, err = es.Locker(-time.Second)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package eventstore
+
+import (
+	"context"
+	"time"
+)
+
+// lagReportInterval is the interval at which the projector
+// reports the lag of named projections to Metrics
+const lagReportInterval = 5 * time.Second
+
+// Metrics receives measurements of event store and projector operations.
+// Errors are passed in so implementations can tell failures apart
+// (eg. errors.Is(err, ErrConcurrencyCheckFailed) for concurrency conflicts).
+// See the prommetrics package for a prometheus implementation
+type Metrics interface {
+	// Appended is called once AppendStream completes
+	Appended(stream string, events, bytes int, took time.Duration, err error)
+
+	// StreamRead is called once ReadStream completes
+	StreamRead(stream string, events int, took time.Duration, err error)
+
+	// Polled is called each time a subscription or an iterator (see SubscribeAll,
+	// SubscribeAllSeq, ReadAllSeq and ReadStreamSeq) polls the event store
+	Polled(events int, took time.Duration, err error)
+
+	// Projected is called each time a projection handles an event
+	Projected(projection string, err error)
+
+	// ProjectionLag is called periodically with the number of events
+	// a named projection is behind the event store
+	ProjectionLag(projection string, lag uint64)
+}
+
+// WithMetrics is an event store option that sets Metrics receiving
+// measurements of appends, stream reads and subscription polls
+func WithMetrics(m Metrics) Option {
+	return func(cfg Cfg) Cfg {
+		cfg.Metrics = m
+
+		return cfg
+	}
+}
+
+// WithProjectorMetrics is a projector option that sets Metrics receiving
+// the number of handled and failed events and the lag of each projection
+func WithProjectorMetrics(m Metrics) ProjectorOpt {
+	return func(cfg ProjectorCfg) ProjectorCfg {
+		cfg.metrics = m
+
+		return cfg
+	}
+}
+
+func (es *EventStore) observer() Metrics {
+	if es.metrics == nil {
+		return nopMetrics{}
+	}
+
+	return es.metrics
+}
+
+// reportLag periodically reports the lag of named projections until ctx is done
+func (p *Projector) reportLag(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-time.After(lagReportInterval):
+			statuses, err := p.Status(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					p.logErr(err)
+				}
+
+				continue
+			}
+
+			for _, s := range statuses {
+				p.cfg.metrics.ProjectionLag(s.Name, s.Lag)
+			}
+		}
+	}
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Appended(string, int, int, time.Duration, error) {}
+
+func (nopMetrics) StreamRead(string, int, time.Duration, error) {}
+
+func (nopMetrics) Polled(int, time.Duration, error) {}
+
+func (nopMetrics) Projected(string, error) {}
+
+func (nopMetrics) ProjectionLag(string, uint64) {}
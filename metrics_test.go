@@ -0,0 +1,126 @@
+package eventstore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedMetrics struct {
+	m         sync.Mutex
+	appended  []int
+	appendErr []error
+	read      []int
+	polled    int
+	projected map[string]int
+}
+
+func (rm *recordedMetrics) Appended(_ string, events, _ int, _ time.Duration, err error) {
+	rm.m.Lock()
+	defer rm.m.Unlock()
+
+	rm.appended = append(rm.appended, events)
+	rm.appendErr = append(rm.appendErr, err)
+}
+
+func (rm *recordedMetrics) StreamRead(_ string, events int, _ time.Duration, _ error) {
+	rm.m.Lock()
+	defer rm.m.Unlock()
+
+	rm.read = append(rm.read, events)
+}
+
+func (rm *recordedMetrics) Polled(_ int, _ time.Duration, _ error) {
+	rm.m.Lock()
+	defer rm.m.Unlock()
+
+	rm.polled++
+}
+
+func (rm *recordedMetrics) Projected(projection string, _ error) {
+	rm.m.Lock()
+	defer rm.m.Unlock()
+
+	if rm.projected == nil {
+		rm.projected = make(map[string]int)
+	}
+
+	rm.projected[projection]++
+}
+
+func (rm *recordedMetrics) ProjectionLag(_ string, _ uint64) {}
+
+func TestEventStoreReportsMetrics(t *testing.T) {
+	var rm recordedMetrics
+
+	es, cleanup := eventStoreWithDec(t, eventstore.NewJSONEncoder(SomeEvent{}), eventstore.WithMetrics(&rm))
+
+	defer cleanup()
+
+	appendUsers(t, es, 2)
+
+	err := es.AppendStream(context.Background(), "stream", eventstore.InitialStreamVersion, toEventToStore(SomeEvent{}))
+
+	assert.ErrorIs(t, err, eventstore.ErrConcurrencyCheckFailed)
+
+	_, err = es.ReadStream(context.Background(), "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := eventstore.NewProjector(es, eventstore.WithProjectorMetrics(&rm))
+
+	p.AddNamed("users", func(_ context.Context, _ eventstore.StoredEvent) error {
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+
+	defer cancel()
+
+	assert.NoError(t, p.Run(ctx))
+
+	rm.m.Lock()
+	defer rm.m.Unlock()
+
+	assert.Equal(t, []int{2, 1}, rm.appended)
+	assert.ErrorIs(t, rm.appendErr[1], eventstore.ErrConcurrencyCheckFailed)
+	assert.Equal(t, []int{2}, rm.read)
+	assert.NotZero(t, rm.polled)
+	assert.Equal(t, map[string]int{"users": 2}, rm.projected)
+}
+
+func TestIteratorsReportPolls(t *testing.T) {
+	var rm recordedMetrics
+
+	es, cleanup := eventStoreWithDec(t, eventstore.NewJSONEncoder(SomeEvent{}), eventstore.WithMetrics(&rm))
+
+	defer cleanup()
+
+	appendUsers(t, es, 3)
+
+	for _, err := range es.ReadAllSeq(context.Background(), eventstore.WithBatchSize(2)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	for _, err := range es.SubscribeAllSeq(ctx) {
+		assert.NoError(t, err)
+
+		break
+	}
+
+	rm.m.Lock()
+	defer rm.m.Unlock()
+
+	assert.Equal(t, 3, rm.polled)
+}
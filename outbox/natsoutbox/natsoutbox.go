@@ -0,0 +1,133 @@
+// Package natsoutbox provides a NATS outbox.Publisher.
+//
+// Events are published as messages carrying the encoded event data
+// (StoredEvent.Raw) with event details set as headers (see Header constants).
+// Meta data is set as headers prefixed with HeaderMetaPrefix.
+package natsoutbox
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Message headers
+const (
+	HeaderID            = "Eventstore-Id"
+	HeaderType          = "Eventstore-Type"
+	HeaderSequence      = "Eventstore-Sequence"
+	HeaderStreamID      = "Eventstore-Stream-Id"
+	HeaderStreamVersion = "Eventstore-Stream-Version"
+	HeaderCausationID   = "Eventstore-Causation-Id"
+	HeaderCorrelationID = "Eventstore-Correlation-Id"
+	HeaderOccurredOn    = "Eventstore-Occurred-On"
+	HeaderMetaPrefix    = "Eventstore-Meta-"
+)
+
+// DefaultSubjectPrefix is the default subject prefix (see WithSubjectPrefix)
+const DefaultSubjectPrefix = "eventstore"
+
+// Cfg (configure using Opt)
+type Cfg struct {
+	subject func(eventstore.StoredEvent) string
+}
+
+// Opt represents publisher configuration option
+type Opt func(Cfg) Cfg
+
+// WithSubject sets the function returning the subject an event is published on
+func WithSubject(subject func(eventstore.StoredEvent) string) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.subject = subject
+
+		return cfg
+	}
+}
+
+// WithSubjectPrefix publishes events on {prefix}.{event type} subjects
+// (eventstore.{event type} by default)
+func WithSubjectPrefix(prefix string) Opt {
+	return WithSubject(func(evt eventstore.StoredEvent) string {
+		return prefix + "." + evt.Type
+	})
+}
+
+// New constructs a publisher publishing events using core NATS.
+// Publishing an event completes once the server has received it, but since core
+// NATS does not persist messages, events are lost if there are no subscribers (see NewJetStream)
+func New(nc *nats.Conn, opts ...Opt) *Publisher {
+	return newPublisher(func(ctx context.Context, msg *nats.Msg, _ string) error {
+		if err := nc.PublishMsg(msg); err != nil {
+			return err
+		}
+
+		if _, ok := ctx.Deadline(); ok {
+			return nc.FlushWithContext(ctx)
+		}
+
+		return nc.Flush()
+	}, opts)
+}
+
+// NewJetStream constructs a publisher publishing events to JetStream.
+// Publishing an event completes once it is acknowledged by the stream and the
+// event ID is used as the message ID, so events published more than once (see outbox)
+// are deduplicated by the stream (within its duplicates window)
+func NewJetStream(js jetstream.JetStream, opts ...Opt) *Publisher {
+	return newPublisher(func(ctx context.Context, msg *nats.Msg, id string) error {
+		_, err := js.PublishMsg(ctx, msg, jetstream.WithMsgID(id))
+
+		return err
+	}, opts)
+}
+
+func newPublisher(publish func(context.Context, *nats.Msg, string) error, opts []Opt) *Publisher {
+	cfg := WithSubjectPrefix(DefaultSubjectPrefix)(Cfg{})
+
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+
+	return &Publisher{
+		cfg:     cfg,
+		publish: publish,
+	}
+}
+
+// Publisher is a NATS outbox.Publisher
+type Publisher struct {
+	cfg     Cfg
+	publish func(context.Context, *nats.Msg, string) error
+}
+
+// Publish publishes the event
+func (p *Publisher) Publish(ctx context.Context, evt eventstore.StoredEvent) error {
+	msg := nats.NewMsg(p.cfg.subject(evt))
+
+	msg.Data = []byte(evt.Raw.Data)
+
+	msg.Header.Set(HeaderID, evt.ID)
+	msg.Header.Set(HeaderType, evt.Type)
+	msg.Header.Set(HeaderSequence, strconv.FormatUint(evt.Sequence, 10))
+	msg.Header.Set(HeaderStreamID, evt.StreamID)
+	msg.Header.Set(HeaderStreamVersion, strconv.Itoa(evt.StreamVersion))
+	msg.Header.Set(HeaderOccurredOn, evt.OccurredOn.Format(time.RFC3339Nano))
+
+	if evt.CausationEventID != nil {
+		msg.Header.Set(HeaderCausationID, *evt.CausationEventID)
+	}
+
+	if evt.CorrelationEventID != nil {
+		msg.Header.Set(HeaderCorrelationID, *evt.CorrelationEventID)
+	}
+
+	for k, v := range evt.Meta {
+		msg.Header.Set(HeaderMetaPrefix+k, v)
+	}
+
+	return p.publish(ctx, msg, evt.ID)
+}
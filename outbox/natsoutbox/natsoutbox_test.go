@@ -0,0 +1,237 @@
+package natsoutbox_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/outbox"
+	"github.com/aneshas/eventstore/outbox/natsoutbox"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func runServer(t *testing.T) *nats.Conn {
+	t.Helper()
+
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		NoLog:     true,
+		NoSigs:    true,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go ns.Start()
+
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		nc.Close()
+		ns.Shutdown()
+		ns.WaitForShutdown()
+	})
+
+	return nc
+}
+
+func storedEvent() eventstore.StoredEvent {
+	causationID := "causation-id"
+
+	return eventstore.StoredEvent{
+		Raw: eventstore.EncodedEvt{
+			Data: `{"N":1}`,
+			Type: "itemAdded",
+		},
+		Meta:             map[string]string{"traceparent": "00-trace-span-01"},
+		ID:               "event-id",
+		Sequence:         7,
+		Type:             "itemAdded",
+		CausationEventID: &causationID,
+		StreamID:         "stream-1",
+		StreamVersion:    3,
+		OccurredOn:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestShouldPublishEventWithHeaders(t *testing.T) {
+	nc := runServer(t)
+
+	sub, err := nc.SubscribeSync("eventstore.>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = natsoutbox.New(nc).Publish(context.Background(), storedEvent())
+
+	assert.NoError(t, err)
+
+	msg, err := sub.NextMsg(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "eventstore.itemAdded", msg.Subject)
+	assert.Equal(t, `{"N":1}`, string(msg.Data))
+	assert.Equal(t, "event-id", msg.Header.Get(natsoutbox.HeaderID))
+	assert.Equal(t, "itemAdded", msg.Header.Get(natsoutbox.HeaderType))
+	assert.Equal(t, "7", msg.Header.Get(natsoutbox.HeaderSequence))
+	assert.Equal(t, "stream-1", msg.Header.Get(natsoutbox.HeaderStreamID))
+	assert.Equal(t, "3", msg.Header.Get(natsoutbox.HeaderStreamVersion))
+	assert.Equal(t, "causation-id", msg.Header.Get(natsoutbox.HeaderCausationID))
+	assert.Empty(t, msg.Header.Get(natsoutbox.HeaderCorrelationID))
+	assert.Equal(t, "2024-01-02T03:04:05Z", msg.Header.Get(natsoutbox.HeaderOccurredOn))
+	assert.Equal(t, "00-trace-span-01", msg.Header.Get(natsoutbox.HeaderMetaPrefix+"traceparent"))
+}
+
+func TestShouldPublishOnCustomSubject(t *testing.T) {
+	nc := runServer(t)
+
+	sub, err := nc.SubscribeSync("accounts.>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = natsoutbox.New(nc, natsoutbox.WithSubject(func(evt eventstore.StoredEvent) string {
+		return "accounts." + evt.StreamID
+	})).Publish(ctx, storedEvent())
+
+	assert.NoError(t, err)
+
+	msg, err := sub.NextMsg(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "accounts.stream-1", msg.Subject)
+}
+
+func jetStream(t *testing.T, nc *nats.Conn) (jetstream.JetStream, jetstream.Stream) {
+	t.Helper()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := js.CreateStream(context.Background(), jetstream.StreamConfig{
+		Name:       "EVENTS",
+		Subjects:   []string{"events.>"},
+		Duplicates: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return js, stream
+}
+
+func TestShouldDeduplicateEventsPublishedToJetStream(t *testing.T) {
+	nc := runServer(t)
+
+	js, stream := jetStream(t, nc)
+
+	pub := natsoutbox.NewJetStream(js, natsoutbox.WithSubjectPrefix("events"))
+
+	ctx := context.Background()
+
+	assert.NoError(t, pub.Publish(ctx, storedEvent()))
+	assert.NoError(t, pub.Publish(ctx, storedEvent()))
+
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, uint64(1), info.State.Msgs)
+
+	msg, err := stream.GetLastMsgForSubject(ctx, "events.itemAdded")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `{"N":1}`, string(msg.Data))
+	assert.Equal(t, "event-id", msg.Header.Get(jetstream.MsgIDHeader))
+}
+
+func TestShouldReportJetStreamPublishErrors(t *testing.T) {
+	nc := runServer(t)
+
+	js, _ := jetStream(t, nc)
+
+	err := natsoutbox.NewJetStream(js, natsoutbox.WithSubjectPrefix("unknown")).
+		Publish(context.Background(), storedEvent())
+
+	assert.ErrorIs(t, err, jetstream.ErrNoStreamResponse)
+}
+
+type itemAdded struct {
+	N int
+}
+
+func TestShouldRelayEventsToJetStream(t *testing.T) {
+	nc := runServer(t)
+
+	js, stream := jetStream(t, nc)
+
+	es, err := eventstore.New(
+		eventstore.NewJSONEncoder(itemAdded{}),
+		eventstore.WithSQLiteDB(filepath.Join(t.TempDir(), "outbox.db")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		_ = es.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = es.AppendStream(ctx, "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+		{Event: itemAdded{N: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	relay := outbox.New(es, natsoutbox.NewJetStream(js, natsoutbox.WithSubjectPrefix("events")))
+
+	go func() {
+		_ = relay.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		info, err := stream.Info(ctx)
+
+		return err == nil && info.State.Msgs == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	msg, err := stream.GetMsg(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `{"N":2}`, string(msg.Data))
+	assert.Equal(t, "2", msg.Header.Get(natsoutbox.HeaderStreamVersion))
+}
@@ -0,0 +1,192 @@
+// Package outbox relays events committed to the event store to external
+// message brokers (see Publisher and natsoutbox).
+//
+// Events are read in the order of their global sequence and the progress of
+// the relay is checkpointed in the event store, so a restarted relay continues
+// where it left off. Delivery is at-least-once: an event is retried until it is
+// published and, since the checkpoint is stored after events are published,
+// events published right before a crash are published again after a restart
+// (publishers should pass the event ID on to the broker for deduplication).
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aneshas/eventstore"
+)
+
+// DefaultName is the default name of the relay (its checkpoint name)
+const DefaultName = "outbox"
+
+// ErrDeliveryFailed is returned when an event could not be published within
+// the configured number of attempts (see WithMaxAttempts)
+var ErrDeliveryFailed = errors.New("outbox: event delivery failed")
+
+// Publisher publishes events to an external message broker.
+// The encoded event is available as StoredEvent.Raw (events are not decoded by the relay)
+type Publisher interface {
+	Publish(ctx context.Context, evt eventstore.StoredEvent) error
+}
+
+// PublisherFunc is a function implementing Publisher
+type PublisherFunc func(ctx context.Context, evt eventstore.StoredEvent) error
+
+// Publish calls the function
+func (f PublisherFunc) Publish(ctx context.Context, evt eventstore.StoredEvent) error {
+	return f(ctx, evt)
+}
+
+// Store is the store events are relayed from and the relay progress is stored in (EventStore implements it)
+type Store interface {
+	eventstore.EventStreamer
+	eventstore.CheckpointStore
+}
+
+// Cfg (configure using Opt)
+type Cfg struct {
+	name          string
+	partitions    int
+	maxAttempts   int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	subOpts       []eventstore.SubAllOpt
+	projectorOpts []eventstore.ProjectorOpt
+}
+
+// Opt represents relay configuration option
+type Opt func(Cfg) Cfg
+
+// WithName sets the name of the relay, which is used as the checkpoint name.
+// Relays publishing to different brokers need to have different names
+func WithName(name string) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.name = name
+
+		return cfg
+	}
+}
+
+// WithPartitions publishes events of different streams on n workers in parallel.
+// Events of the same stream are always published in order (see eventstore.WithPartitions)
+func WithPartitions(n int) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.partitions = n
+
+		return cfg
+	}
+}
+
+// WithMaxAttempts sets the number of attempts to publish an event after which
+// the relay restarts from the last checkpoint (0, the default, retries until the relay is stopped)
+func WithMaxAttempts(n int) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.maxAttempts = n
+
+		return cfg
+	}
+}
+
+// WithBackoff sets the exponential backoff between publishing attempts
+// (100ms doubling up to 30s by default)
+func WithBackoff(min, max time.Duration) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.minBackoff = min
+		cfg.maxBackoff = max
+
+		return cfg
+	}
+}
+
+// WithEventTypes only relays events of the given types (see eventstore.WithEventTypes)
+func WithEventTypes(types ...string) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.subOpts = append(cfg.subOpts, eventstore.WithEventTypes(types...))
+
+		return cfg
+	}
+}
+
+// WithProjectorOpts sets options of the projector running the relay
+// (eg. eventstore.WithLocker in order to run a single relay instance at a time)
+func WithProjectorOpts(opts ...eventstore.ProjectorOpt) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.projectorOpts = append(cfg.projectorOpts, opts...)
+
+		return cfg
+	}
+}
+
+// New constructs a new outbox relay
+func New(s Store, pub Publisher, opts ...Opt) *Relay {
+	cfg := Cfg{
+		name:       DefaultName,
+		partitions: 1,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+	}
+
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+
+	return &Relay{
+		store: s,
+		pub:   pub,
+		cfg:   cfg,
+	}
+}
+
+// Relay relays events from the event store to a Publisher
+type Relay struct {
+	store Store
+	pub   Publisher
+	cfg   Cfg
+}
+
+// Run runs the relay until ctx is canceled
+func (r *Relay) Run(ctx context.Context) error {
+	p := eventstore.NewProjector(
+		r.store,
+		append([]eventstore.ProjectorOpt{eventstore.WithCheckpointStore(r.store)}, r.cfg.projectorOpts...)...,
+	)
+
+	p.AddNamed(
+		r.cfg.name,
+		r.publish,
+		eventstore.WithPartitions(r.cfg.partitions),
+		eventstore.WithSubscriptionOpts(append([]eventstore.SubAllOpt{eventstore.WithLazyDecoding()}, r.cfg.subOpts...)...),
+	)
+
+	return p.Run(ctx)
+}
+
+func (r *Relay) publish(ctx context.Context, evt eventstore.StoredEvent) error {
+	backoff := r.cfg.minBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := r.pub.Publish(ctx, evt)
+		if err == nil {
+			return nil
+		}
+
+		if r.cfg.maxAttempts > 0 && attempt >= r.cfg.maxAttempts {
+			return fmt.Errorf("%w: event %s (sequence %d): %w", ErrDeliveryFailed, evt.ID, evt.Sequence, err)
+		}
+
+		t := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+
+			return ctx.Err()
+
+		case <-t.C:
+		}
+
+		backoff = min(backoff*2, r.cfg.maxBackoff)
+	}
+}
@@ -0,0 +1,239 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/outbox"
+	"github.com/stretchr/testify/assert"
+)
+
+type itemAdded struct {
+	N int
+}
+
+type itemRemoved struct {
+	N int
+}
+
+type publisher struct {
+	mu     sync.Mutex
+	fail   int
+	calls  int
+	events []eventstore.StoredEvent
+	done   chan struct{}
+	want   int
+}
+
+func newPublisher(want int) *publisher {
+	return &publisher{
+		want: want,
+		done: make(chan struct{}),
+	}
+}
+
+// Publish fails the first fail calls and records published events
+func (p *publisher) Publish(_ context.Context, evt eventstore.StoredEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+
+	if p.fail > 0 {
+		p.fail--
+
+		return errors.New("broker unavailable")
+	}
+
+	p.events = append(p.events, evt)
+
+	if len(p.events) == p.want {
+		close(p.done)
+	}
+
+	return nil
+}
+
+func (p *publisher) published() []eventstore.StoredEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]eventstore.StoredEvent{}, p.events...)
+}
+
+func newEventStore(t *testing.T) *eventstore.EventStore {
+	t.Helper()
+
+	es, err := eventstore.New(
+		eventstore.NewJSONEncoder(itemAdded{}, itemRemoved{}),
+		eventstore.WithSQLiteDB(filepath.Join(t.TempDir(), "outbox.db")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = es.Close()
+	})
+
+	return es
+}
+
+func appendItems(t *testing.T, es *eventstore.EventStore, stream string, version int, from, to int) {
+	t.Helper()
+
+	var evts []eventstore.EventToStore
+
+	for n := from; n <= to; n++ {
+		evts = append(evts, eventstore.EventToStore{Event: itemAdded{N: n}})
+	}
+
+	err := es.AppendStream(context.Background(), stream, version, evts)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func run(t *testing.T, r *outbox.Relay, p *publisher) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_ = r.Run(ctx)
+	}()
+
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("events not published")
+	}
+
+	cancel()
+
+	<-done
+}
+
+func sequences(evts []eventstore.StoredEvent) []uint64 {
+	var seqs []uint64
+
+	for _, evt := range evts {
+		seqs = append(seqs, evt.Sequence)
+	}
+
+	return seqs
+}
+
+func TestShouldPublishEventsInOrderRetryingFailures(t *testing.T) {
+	es := newEventStore(t)
+
+	appendItems(t, es, "stream-1", 0, 1, 3)
+
+	p := newPublisher(3)
+	p.fail = 2
+
+	run(t, outbox.New(es, p, outbox.WithBackoff(time.Millisecond, 5*time.Millisecond)), p)
+
+	evts := p.published()
+
+	assert.Equal(t, []uint64{1, 2, 3}, sequences(evts))
+	assert.Equal(t, 5, p.calls)
+	assert.Equal(t, "itemAdded", evts[0].Raw.Type)
+	assert.JSONEq(t, `{"N":1}`, evts[0].Raw.Data)
+	assert.Nil(t, evts[0].Event)
+}
+
+func TestShouldContinueFromCheckpoint(t *testing.T) {
+	es := newEventStore(t)
+
+	appendItems(t, es, "stream-1", 0, 1, 3)
+
+	p := newPublisher(3)
+
+	run(t, outbox.New(es, p), p)
+
+	appendItems(t, es, "stream-1", 3, 4, 5)
+
+	p = newPublisher(2)
+
+	run(t, outbox.New(es, p), p)
+
+	assert.Equal(t, []uint64{4, 5}, sequences(p.published()))
+
+	seq, err := es.Checkpoint(context.Background(), outbox.DefaultName)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), seq)
+}
+
+func TestShouldRestartFromCheckpointAfterMaxAttempts(t *testing.T) {
+	es := newEventStore(t)
+
+	appendItems(t, es, "stream-1", 0, 1, 2)
+
+	p := newPublisher(2)
+	p.fail = 3
+
+	run(t, outbox.New(
+		es,
+		p,
+		outbox.WithName("restarting"),
+		outbox.WithMaxAttempts(2),
+		outbox.WithBackoff(time.Millisecond, time.Millisecond),
+	), p)
+
+	assert.Equal(t, []uint64{1, 2}, sequences(p.published()))
+	assert.Equal(t, 5, p.calls)
+}
+
+func TestShouldPreserveOrderPerStreamWhenPartitioned(t *testing.T) {
+	es := newEventStore(t)
+
+	for i := range 4 {
+		appendItems(t, es, fmt.Sprintf("stream-%d", i), 0, 1, 5)
+	}
+
+	p := newPublisher(20)
+
+	run(t, outbox.New(es, p, outbox.WithPartitions(3)), p)
+
+	byStream := make(map[string][]int)
+
+	for _, evt := range p.published() {
+		byStream[evt.StreamID] = append(byStream[evt.StreamID], evt.StreamVersion)
+	}
+
+	assert.Len(t, byStream, 4)
+
+	for stream, versions := range byStream {
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, versions, stream)
+	}
+}
+
+func TestShouldOnlyPublishFilteredEventTypes(t *testing.T) {
+	es := newEventStore(t)
+
+	err := es.AppendStream(context.Background(), "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+		{Event: itemRemoved{N: 1}},
+		{Event: itemAdded{N: 2}},
+	})
+
+	assert.NoError(t, err)
+
+	p := newPublisher(2)
+
+	run(t, outbox.New(es, p, outbox.WithEventTypes("itemAdded")), p)
+
+	assert.Equal(t, []uint64{1, 3}, sequences(p.published()))
+}
@@ -0,0 +1,161 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// PoisonEventError indicates that a stored event could not be decoded
+// (eg. malformed event data or meta). It is produced by reads and subscriptions
+// unless handled by a poison handler (see WithPoisonHandler and WithQuarantine).
+// Events of types not registered with the encoder are not poison events,
+// they are handled according to the decode policy (see DecodePolicy).
+// Projections report poison events (see Projector.Status) and resubscribe until
+// the event is repaired or handled
+type PoisonEventError struct {
+	Sequence uint64
+	ID       string
+	StreamID string
+	Type     string
+	Err      error
+}
+
+func newPoisonEventError(evt gormEvent, err error) *PoisonEventError {
+	return &PoisonEventError{
+		Sequence: evt.Sequence,
+		ID:       evt.ID,
+		StreamID: evt.StreamID,
+		Type:     evt.Type,
+		Err:      err,
+	}
+}
+
+// Error returns error message
+func (e *PoisonEventError) Error() string {
+	return fmt.Sprintf("poison event %d (%s %s): %v", e.Sequence, e.Type, e.ID, e.Err)
+}
+
+// Unwrap returns the underlying decoding error
+func (e *PoisonEventError) Unwrap() error { return e.Err }
+
+func isPoison(err error) bool {
+	var perr *PoisonEventError
+
+	return errors.As(err, &perr)
+}
+
+// WithPoisonHandler is a subscription / read all option that specifies a handler
+// called for each event which could not be decoded. If the handler returns nil the
+// event is skipped, otherwise reading fails with the returned error
+func WithPoisonHandler(h func(context.Context, *PoisonEventError) error) SubAllOpt {
+	return func(cfg SubAllConfig) SubAllConfig {
+		cfg.poison = h
+
+		return cfg
+	}
+}
+
+// WithQuarantine is a subscription / read all option that records events which
+// could not be decoded in the quarantine table (see EventStore.Quarantined) and
+// skips them. If a poison handler is provided as well, it is called after
+// the event has been recorded and decides whether the event is skipped
+func WithQuarantine() SubAllOpt {
+	return func(cfg SubAllConfig) SubAllConfig {
+		cfg.quarantine = true
+
+		return cfg
+	}
+}
+
+type gormQuarantinedEvent struct {
+	Sequence      uint64 `gorm:"primaryKey;autoIncrement:false"`
+	EventID       string
+	StreamID      string
+	Type          string
+	Error         string
+	QuarantinedAt time.Time
+}
+
+// TableName returns gorm table name
+func (qe *gormQuarantinedEvent) TableName() string { return "quarantined_event" }
+
+// QuarantinedEvent represents an event recorded in the quarantine table
+type QuarantinedEvent struct {
+	Sequence      uint64
+	EventID       string
+	StreamID      string
+	Type          string
+	Error         string
+	QuarantinedAt time.Time
+}
+
+// Quarantined returns events recorded in the quarantine table
+func (es *EventStore) Quarantined(ctx context.Context) ([]QuarantinedEvent, error) {
+	var qes []gormQuarantinedEvent
+
+	if err := es.conn(ctx).
+		Order("sequence asc").
+		Find(&qes).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]QuarantinedEvent, len(qes))
+
+	for i, qe := range qes {
+		out[i] = QuarantinedEvent(qe)
+	}
+
+	return out, nil
+}
+
+// ReleaseQuarantined removes the event from the quarantine table
+// (eg. once the event has been repaired and reprocessed)
+func (es *EventStore) ReleaseQuarantined(ctx context.Context, seq uint64) error {
+	return es.conn(ctx).
+		Where("sequence = ?", seq).
+		Delete(&gormQuarantinedEvent{}).Error
+}
+
+func (es *EventStore) quarantine(ctx context.Context, perr *PoisonEventError) error {
+	return es.DB.
+		WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&gormQuarantinedEvent{
+			Sequence:      perr.Sequence,
+			EventID:       perr.ID,
+			StreamID:      perr.StreamID,
+			Type:          perr.Type,
+			Error:         perr.Err.Error(),
+			QuarantinedAt: time.Now().UTC(),
+		}).Error
+}
+
+// decodeSub decodes the event for the subscription, handling poison events
+// according to the subscription configuration
+func (es *EventStore) decodeSub(ctx context.Context, evt gormEvent, cfg SubAllConfig) (StoredEvent, bool, error) {
+	decoded, ok, err := es.decodeLazy(evt, cfg)
+
+	var perr *PoisonEventError
+
+	if !errors.As(err, &perr) || (cfg.poison == nil && !cfg.quarantine) {
+		return decoded, ok, err
+	}
+
+	if cfg.quarantine {
+		if err := es.quarantine(ctx, perr); err != nil {
+			return StoredEvent{}, false, err
+		}
+	}
+
+	if cfg.poison != nil {
+		if err := cfg.poison(ctx, perr); err != nil {
+			return StoredEvent{}, false, err
+		}
+	}
+
+	return StoredEvent{}, false, nil
+}
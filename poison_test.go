@@ -0,0 +1,231 @@
+package eventstore_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/tx/v2"
+	"github.com/aneshas/tx/v2/gormtx"
+	"github.com/stretchr/testify/assert"
+)
+
+func appendPoisoned(t *testing.T, es *eventstore.EventStore) {
+	t.Helper()
+
+	appendUsers(t, es, 3)
+
+	if err := es.DB.Exec("UPDATE event SET data = 'malformed' WHERE sequence = 2").Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadAllReportsPoisonEvent(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendPoisoned(t, es)
+
+	_, err := es.ReadAll(context.Background())
+
+	var perr *eventstore.PoisonEventError
+
+	if !errors.As(err, &perr) {
+		t.Fatalf("poison event error expected, got %v", err)
+	}
+
+	assert.Equal(t, uint64(2), perr.Sequence)
+	assert.Equal(t, "SomeEvent", perr.Type)
+}
+
+func TestSubscribeAllSkipsPoisonEventWithHandler(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendPoisoned(t, es)
+
+	var poisoned []uint64
+
+	sub, err := es.SubscribeAll(
+		context.Background(),
+		eventstore.WithPoisonHandler(func(_ context.Context, perr *eventstore.PoisonEventError) error {
+			poisoned = append(poisoned, perr.Sequence)
+
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sub.Close()
+
+	evts := readAllSub(t, sub, 2)
+
+	assert.Equal(t, SomeEvent{UserID: "user-3"}, evts[1].Event)
+	assert.Equal(t, []uint64{2}, poisoned)
+}
+
+func TestPoisonHandlerErrorFailsRead(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendPoisoned(t, es)
+
+	anErr := errors.New("an error")
+
+	_, err := es.ReadAll(
+		context.Background(),
+		eventstore.WithPoisonHandler(func(_ context.Context, _ *eventstore.PoisonEventError) error {
+			return anErr
+		}),
+	)
+
+	assert.ErrorIs(t, err, anErr)
+}
+
+func TestQuarantineRecordsPoisonEvents(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendPoisoned(t, es)
+
+	ctx := context.Background()
+
+	evts, err := es.ReadAll(ctx, eventstore.WithQuarantine())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, evts, 2)
+
+	quarantined, err := es.Quarantined(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, quarantined, 1)
+	assert.Equal(t, uint64(2), quarantined[0].Sequence)
+	assert.Equal(t, "stream", quarantined[0].StreamID)
+	assert.NotEmpty(t, quarantined[0].Error)
+
+	assert.NoError(t, es.ReleaseQuarantined(ctx, 2))
+
+	quarantined, err = es.Quarantined(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Empty(t, quarantined)
+}
+
+func TestQuarantineLeavesUnregisteredEventsToDecodePolicy(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUnknown(t, es)
+
+	ctx := context.Background()
+
+	handled := false
+
+	_, err := es.ReadAll(
+		ctx,
+		eventstore.WithQuarantine(),
+		eventstore.WithPoisonHandler(func(_ context.Context, _ *eventstore.PoisonEventError) error {
+			handled = true
+
+			return nil
+		}),
+	)
+
+	var perr *eventstore.PoisonEventError
+
+	assert.ErrorIs(t, err, eventstore.ErrEventNotRegistered)
+	assert.False(t, errors.As(err, &perr))
+	assert.False(t, handled)
+
+	quarantined, err := es.Quarantined(ctx)
+
+	assert.NoError(t, err)
+	assert.Empty(t, quarantined)
+}
+
+func TestProjectorShouldReportAndResubscribeOnPoisonEvent(t *testing.T) {
+	cases := []struct {
+		name string
+		opts func(es *eventstore.EventStore) []eventstore.ProjectionOpt
+	}{
+		{name: "sequential", opts: func(_ *eventstore.EventStore) []eventstore.ProjectionOpt { return nil }},
+		{name: "partitioned", opts: func(_ *eventstore.EventStore) []eventstore.ProjectionOpt {
+			return []eventstore.ProjectionOpt{eventstore.WithPartitions(2)}
+		}},
+		{name: "transactional", opts: func(es *eventstore.EventStore) []eventstore.ProjectionOpt {
+			return []eventstore.ProjectionOpt{eventstore.WithTransaction(tx.New(gormtx.NewDB(es.DB)), 1)}
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			es, cleanup := eventStore(t)
+
+			defer cleanup()
+
+			appendPoisoned(t, es)
+
+			ctx, cancel := context.WithCancel(context.Background())
+
+			defer cancel()
+
+			var (
+				m   sync.Mutex
+				got []uint64
+			)
+
+			p := eventstore.NewProjector(es)
+
+			p.AddNamed("users", func(_ context.Context, evt eventstore.StoredEvent) error {
+				m.Lock()
+				defer m.Unlock()
+
+				got = append(got, evt.Sequence)
+
+				return nil
+			}, tc.opts(es)...)
+
+			go func() { _ = p.Run(ctx) }()
+
+			assert.Eventually(t, func() bool {
+				statuses, err := p.Status(ctx)
+
+				return err == nil && statuses[0].Errors > 0 && strings.Contains(statuses[0].LastError, "poison event 2")
+			}, 2*time.Second, 20*time.Millisecond)
+
+			err := es.DB.Exec(`UPDATE event SET data = '{"UserID":"user-2"}' WHERE sequence = 2`).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Eventually(t, func() bool {
+				seq, err := es.Checkpoint(ctx, "users")
+
+				return err == nil && seq == 3
+			}, 5*time.Second, 50*time.Millisecond)
+
+			m.Lock()
+			defer m.Unlock()
+
+			// events are redelivered if the (transactional) checkpoint fails to save
+			assert.Subset(t, got, []uint64{1, 2, 3})
+		})
+	}
+}
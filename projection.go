@@ -3,10 +3,16 @@ package eventstore
 import (
 	"context"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"io"
 	"log"
+	"slices"
 	"sync"
 	"time"
+
+	"github.com/aneshas/eventstore/internal/tracing"
+	"github.com/aneshas/tx/v2"
 )
 
 // EventStreamer represents an event stream that can be subscribed to
@@ -15,12 +21,98 @@ type EventStreamer interface {
 	SubscribeAll(context.Context, ...SubAllOpt) (Subscription, error)
 }
 
+// checkpointEvery indicates after how many processed events the checkpoint
+// of a named projection is stored (it is also stored each time the projection
+// catches up with the event store)
+const checkpointEvery = 100
+
+// poisonRetryInterval is the interval at which a projection whose subscription
+// ended with a poison event is resubscribed
+const poisonRetryInterval = time.Second
+
 // NewProjector constructs a Projector
+// If the event streamer also implements CheckpointStore (EventStore does) it
+// will be used to store the progress of named projections
 // TODO Configure logger, pollInterval, and retry
-func NewProjector(s EventStreamer) *Projector {
+func NewProjector(s EventStreamer, opts ...ProjectorOpt) *Projector {
+	var cfg ProjectorCfg
+
+	if cs, ok := s.(CheckpointStore); ok {
+		cfg.checkpoints = cs
+	}
+
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+
+	if cfg.metrics == nil {
+		cfg.metrics = nopMetrics{}
+	}
+
 	return &Projector{
 		streamer: s,
 		logger:   log.Default(),
+		cfg:      cfg,
+	}
+}
+
+// ProjectorCfg (configure using ProjectorOpt)
+type ProjectorCfg struct {
+	checkpoints CheckpointStore
+	locker      Locker
+	metrics     Metrics
+}
+
+// ProjectorOpt represents projector configuration option
+type ProjectorOpt func(ProjectorCfg) ProjectorCfg
+
+// WithCheckpointStore is a projector option that sets the store used
+// to persist the progress of named projections
+func WithCheckpointStore(cs CheckpointStore) ProjectorOpt {
+	return func(cfg ProjectorCfg) ProjectorCfg {
+		cfg.checkpoints = cs
+
+		return cfg
+	}
+}
+
+// ProjectionCfg (configure using ProjectionOpt)
+type ProjectionCfg struct {
+	partitions int
+	subOpts    []SubAllOpt
+	transactor tx.Transactor
+	txBatch    int
+
+	flush         func(context.Context) error
+	flushSize     int
+	flushInterval time.Duration
+	reset         func(context.Context) error
+	shadow        Shadow
+}
+
+// ProjectionOpt represents named projection configuration option
+type ProjectionOpt func(ProjectionCfg) ProjectionCfg
+
+// WithPartitions is a projection option that enables parallel projection of
+// events by hashing their stream id onto n workers. Events belonging to the same
+// stream are always handled by the same worker, thus preserving their order.
+// The checkpoint only advances up to the sequence below which all dispatched
+// events have been handled, so no events are skipped after a restart
+func WithPartitions(n int) ProjectionOpt {
+	return func(cfg ProjectionCfg) ProjectionCfg {
+		cfg.partitions = n
+
+		return cfg
+	}
+}
+
+// WithSubscriptionOpts is a projection option that sets additional options
+// used when subscribing to the event stream (eg. WithEventTypes or WithBatchSize)
+func WithSubscriptionOpts(opts ...SubAllOpt) ProjectionOpt {
+	return func(cfg ProjectionCfg) ProjectionCfg {
+		cfg.subOpts = append(cfg.subOpts, opts...)
+
+		return cfg
 	}
 }
 
@@ -29,86 +121,655 @@ func NewProjector(s EventStreamer) *Projector {
 // individual projection in an asynchronous manner
 type Projector struct {
 	streamer    EventStreamer
-	projections []Projection
+	projections []registration
 	logger      *log.Logger
+	cfg         ProjectorCfg
+
+	mu         sync.Mutex
+	wg         sync.WaitGroup
+	runners    map[string]*runner
+	rebuilding map[string]bool
+	stats      map[string]*stats
+	statNames  []string
 }
 
 // Projection is basically a function which needs to handle a stored event.
 // It will be called for each event that comes in
 type Projection func(StoredEvent) error
 
+// ProjectionFunc is a context aware Projection. The context passed in
+// is canceled once the projector stops
+type ProjectionFunc func(ctx context.Context, evt StoredEvent) error
+
+type registration struct {
+	name    string
+	project ProjectionFunc
+	cfg     ProjectionCfg
+
+	// caughtUp (if set) is called each time the projection has handled
+	// all events currently in the event store
+	caughtUp func()
+
+	stats   *stats
+	metrics Metrics
+}
+
+// handle passes the event to the projection within a span
+// continuing the trace the event was appended in (see StartEventSpan)
+func (r registration) handle(ctx context.Context, evt StoredEvent) error {
+	ctx, span := StartEventSpan(
+		ctx,
+		tracing.Tracer(),
+		"eventstore.Project",
+		evt,
+		AttrProjection.String(r.name),
+	)
+
+	err := r.project(ctx, evt)
+
+	r.metrics.Projected(r.name, err)
+
+	return tracing.EndSpan(span, err)
+}
+
 // Add effectively registers a projection with the projector
 // Make sure to add all of your projections before calling Run
 func (p *Projector) Add(projections ...Projection) {
-	p.projections = append(p.projections, projections...)
+	for _, projection := range projections {
+		p.projections = append(p.projections, registration{
+			project: func(_ context.Context, evt StoredEvent) error {
+				return projection(evt)
+			},
+			metrics: p.cfg.metrics,
+		})
+	}
+}
+
+// AddNamed registers a named projection with the projector. The progress of
+// named projections is checkpointed (see CheckpointStore), so they continue
+// where they left off when the projector is restarted.
+// Make sure to add all of your projections before calling Run
+func (p *Projector) AddNamed(name string, projection ProjectionFunc, opts ...ProjectionOpt) {
+	var cfg ProjectionCfg
+
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+
+	p.projections = append(p.projections, registration{
+		name:    name,
+		project: projection,
+		cfg:     cfg,
+		stats:   p.statsFor(name),
+		metrics: p.cfg.metrics,
+	})
 }
 
 // Run will start the projector
 func (p *Projector) Run(ctx context.Context) error {
-	var wg sync.WaitGroup
+	for _, r := range p.projections {
+		if err := p.validate(r); err != nil {
+			return err
+		}
+	}
 
-	for _, projection := range p.projections {
-		wg.Add(1)
+	for _, r := range p.projections {
+		p.start(ctx, r)
+	}
 
-		go func(projection Projection) {
-			defer wg.Done()
+	if _, ok := p.cfg.metrics.(nopMetrics); !ok {
+		go p.reportLag(ctx)
+	}
 
-			for {
-				// TODO retry with backoff
-				sub, err := p.streamer.SubscribeAll(ctx)
-				if err != nil {
-					p.logErr(err)
+	p.wg.Wait()
 
-					return
-				}
+	return nil
+}
 
-				if err := p.run(ctx, sub, projection); err != nil {
-					sub.Close()
+// runner represents a running named projection which can be
+// paused and resumed (eg. in order to be rebuilt)
+type runner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
 
-					continue
-				}
+func (p *Projector) start(ctx context.Context, r registration) {
+	rctx, cancel := context.WithCancel(ctx)
+
+	rn := runner{
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	if r.name != "" {
+		p.mu.Lock()
+
+		if p.runners == nil {
+			p.runners = make(map[string]*runner)
+		}
+
+		p.runners[r.name] = &rn
+
+		p.mu.Unlock()
+	}
+
+	p.wg.Add(1)
 
-				sub.Close()
+	go func() {
+		defer p.wg.Done()
+		defer close(rn.done)
+
+		p.runProjection(rctx, r)
+
+		if r.name == "" {
+			return
+		}
 
+		// Drop the runner once stopped, so a stopped projector does not
+		// restart it (see resume)
+		p.mu.Lock()
+
+		if p.runners[r.name] == &rn {
+			delete(p.runners, r.name)
+		}
+
+		p.mu.Unlock()
+	}()
+}
+
+func (p *Projector) runProjection(ctx context.Context, r registration) {
+	defer r.stats.setState(ProjectionStopped)
+
+	if p.cfg.locker == nil || r.name == "" {
+		p.project(ctx, r)
+
+		return
+	}
+
+	for {
+		r.stats.setState(ProjectionStandby)
+
+		lease, err := p.cfg.locker.Acquire(ctx, r.name)
+		if err != nil {
+			if ctx.Err() != nil {
 				return
 			}
-		}(projection)
+
+			p.logErr(err)
+			r.stats.failed(err)
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-time.After(leaseRetryInterval):
+				continue
+			}
+		}
+
+		lctx, cancel := context.WithCancel(ctx)
+
+		go func() {
+			select {
+			case <-lease.Lost():
+				cancel()
+
+			case <-lctx.Done():
+			}
+		}()
+
+		p.project(lctx, r)
+
+		cancel()
+
+		if err := lease.Release(context.WithoutCancel(ctx)); err != nil {
+			p.logErr(err)
+		}
+
+		select {
+		case <-lease.Lost():
+			if ctx.Err() == nil {
+				continue
+			}
+
+		default:
+		}
+
+		return
 	}
+}
 
-	wg.Wait()
+func (p *Projector) project(ctx context.Context, r registration) {
+	for {
+		cp, err := p.checkpointer(ctx, r)
+		if err != nil {
+			p.logErr(err)
+			r.stats.failed(err)
+
+			return
+		}
+
+		// TODO retry with backoff
+		sub, err := p.streamer.SubscribeAll(ctx, append(slices.Clip(r.cfg.subOpts), WithOffset(int(cp.saved)))...)
+		if err != nil {
+			p.logErr(err)
+			r.stats.failed(err)
+
+			return
+		}
+
+		r.stats.started(cp.saved)
+
+		if err := p.run(ctx, sub, r, cp); err != nil {
+			r.stats.failed(err)
+			sub.Close()
+
+			if !isPoison(err) {
+				continue
+			}
+
+			// The subscription ends with a poison event, so the projection is
+			// resubscribed (reporting the error) until the event is repaired or handled
+			// eg. by a poison handler or quarantine (see WithSubscriptionOpts)
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-time.After(poisonRetryInterval):
+				continue
+			}
+		}
+
+		sub.Close()
+
+		return
+	}
+}
+
+func (p *Projector) validate(r registration) error {
+	if r.cfg.flush != nil && (r.cfg.transactor != nil || r.cfg.partitions > 1) {
+		return fmt.Errorf("flushing projection %s cannot be transactional or partitioned", r.name)
+	}
+
+	if r.cfg.transactor == nil {
+		return nil
+	}
+
+	if r.name == "" || p.cfg.checkpoints == nil {
+		return fmt.Errorf("transactional projection %s requires a name and a checkpoint store", r.name)
+	}
+
+	if r.cfg.partitions > 1 {
+		return fmt.Errorf("transactional projection %s cannot be partitioned", r.name)
+	}
 
 	return nil
 }
 
-func (p *Projector) run(ctx context.Context, sub Subscription, projection Projection) error {
+func (p *Projector) run(ctx context.Context, sub Subscription, r registration, cp *checkpointer) error {
+	if r.cfg.transactor != nil {
+		return p.runTransactional(ctx, sub, r, cp)
+	}
+
+	if r.cfg.partitions > 1 {
+		return p.runPartitioned(ctx, sub, r, cp)
+	}
+
+	var (
+		last    = cp.saved
+		pending int
+		timer   <-chan time.Time
+		size    = checkpointEvery
+	)
+
+	if r.cfg.flush != nil {
+		size = r.cfg.flushSize
+	}
+
+	// commit flushes the projection (if flushing is enabled) and
+	// stores the checkpoint of the events handled so far
+	commit := func(ctx context.Context) error {
+		if r.cfg.flush != nil && pending > 0 {
+			if err := r.cfg.flush(ctx); err != nil {
+				p.logErr(err)
+
+				return err
+			}
+		}
+
+		cp.save(ctx, last)
+
+		pending = 0
+		timer = nil
+
+		return nil
+	}
+
 	for {
 		select {
 		case data := <-sub.EventData:
-			err := projection(data)
+			err := r.handle(ctx, data)
 			if err != nil {
 				p.logErr(err)
 				// TODO retry with backoff
 
+				return errors.Join(err, commit(context.WithoutCancel(ctx)))
+			}
+
+			last = data.Sequence
+			pending++
+
+			r.stats.processed(data.Sequence, data.OccurredOn)
+
+			if pending == 1 && r.cfg.flushInterval > 0 {
+				timer = time.After(r.cfg.flushInterval)
+			}
+
+			if pending >= size {
+				if err := commit(ctx); err != nil {
+					return err
+				}
+			}
+
+		case <-timer:
+			if err := commit(ctx); err != nil {
 				return err
 			}
 
 		case err := <-sub.Err:
 			if err != nil {
 				if errors.Is(err, io.EOF) {
+					if seq, ok := sub.skipped(); ok && seq > last {
+						last = seq
+
+						r.stats.advance(seq)
+					}
+
+					if r.cfg.flush == nil {
+						_ = commit(ctx)
+					}
+
+					if r.caughtUp != nil && len(sub.EventData) == 0 {
+						r.caughtUp()
+					}
+
 					break
 				}
 
 				if errors.Is(err, ErrSubscriptionClosedByClient) {
-					return nil
+					return commit(context.WithoutCancel(ctx))
 				}
 
 				p.logErr(err)
+
+				if isPoison(err) {
+					return errors.Join(err, commit(context.WithoutCancel(ctx)))
+				}
 			}
 
 		case <-ctx.Done():
+			return commit(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+type partitionedEvent struct {
+	ticket uint64
+	evt    StoredEvent
+}
+
+func (p *Projector) runPartitioned(ctx context.Context, sub Subscription, r registration, cp *checkpointer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg         sync.WaitGroup
+		errs       = make(chan error, 1)
+		tracker    = newWatermark(cp.saved)
+		workers    = make([]chan partitionedEvent, r.cfg.partitions)
+		dispatched int
+	)
+
+	for i := range workers {
+		workers[i] = make(chan partitionedEvent, checkpointEvery)
+
+		wg.Add(1)
+
+		go func(work chan partitionedEvent) {
+			defer wg.Done()
+
+			for w := range work {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				if err := r.handle(ctx, w.evt); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+
+					cancel()
+
+					continue
+				}
+
+				tracker.complete(w.ticket)
+
+				r.stats.processed(tracker.position())
+			}
+		}(workers[i])
+	}
+
+	stop := func() error {
+		for _, work := range workers {
+			close(work)
+		}
+
+		wg.Wait()
+
+		cp.save(ctx, tracker.sequence())
+
+		select {
+		case err := <-errs:
+			p.logErr(err)
+
+			return err
+
+		default:
 			return nil
 		}
 	}
+
+	for {
+		select {
+		case data := <-sub.EventData:
+			w := workers[partition(data.StreamID, len(workers))]
+
+			select {
+			case w <- partitionedEvent{ticket: tracker.dispatch(data), evt: data}:
+			case <-ctx.Done():
+				return stop()
+			}
+
+			dispatched++
+
+			if dispatched%checkpointEvery == 0 {
+				cp.save(ctx, tracker.sequence())
+			}
+
+		case err := <-sub.Err:
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					if seq, ok := sub.skipped(); ok && tracker.skip(seq) {
+						r.stats.advance(seq)
+					}
+
+					cp.save(ctx, tracker.sequence())
+
+					if r.caughtUp != nil && len(sub.EventData) == 0 && tracker.idle() {
+						r.caughtUp()
+					}
+
+					break
+				}
+
+				if errors.Is(err, ErrSubscriptionClosedByClient) {
+					return stop()
+				}
+
+				p.logErr(err)
+
+				if isPoison(err) {
+					return errors.Join(err, stop())
+				}
+			}
+
+		case <-ctx.Done():
+			return stop()
+		}
+	}
+}
+
+func partition(stream string, n int) int {
+	h := fnv.New32a()
+
+	_, _ = h.Write([]byte(stream))
+
+	return int(h.Sum32() % uint32(n))
+}
+
+// watermark keeps track of events dispatched to partition workers and
+// reports the sequence up to which all of them have been handled
+type watermark struct {
+	mu   sync.Mutex
+	next uint64
+	low  uint64
+	evts map[uint64]StoredEvent
+	done map[uint64]bool
+	seq  uint64
+	on   time.Time
+}
+
+func newWatermark(seq uint64) *watermark {
+	return &watermark{
+		evts: make(map[uint64]StoredEvent),
+		done: make(map[uint64]bool),
+		seq:  seq,
+	}
+}
+
+func (w *watermark) dispatch(evt StoredEvent) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ticket := w.next
+
+	w.evts[ticket] = evt
+	w.next++
+
+	return ticket
+}
+
+func (w *watermark) complete(ticket uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.done[ticket] = true
+
+	for w.done[w.low] {
+		w.seq = w.evts[w.low].Sequence
+		w.on = w.evts[w.low].OccurredOn
+
+		delete(w.done, w.low)
+		delete(w.evts, w.low)
+
+		w.low++
+	}
+}
+
+// skip advances the sequence past the events skipped while reading
+// provided that all dispatched events have been handled
+func (w *watermark) skip(seq uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.low != w.next || seq <= w.seq {
+		return false
+	}
+
+	w.seq = seq
+
+	return true
+}
+
+func (w *watermark) position() (uint64, time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.seq, w.on
+}
+
+func (w *watermark) idle() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.low == w.next
+}
+
+func (w *watermark) sequence() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.seq
+}
+
+type checkpointer struct {
+	store  CheckpointStore
+	name   string
+	saved  uint64
+	logErr func(error)
+}
+
+func (p *Projector) checkpointer(ctx context.Context, r registration) (*checkpointer, error) {
+	cp := checkpointer{
+		name:   r.name,
+		logErr: p.logErr,
+	}
+
+	if r.name == "" || p.cfg.checkpoints == nil {
+		return &cp, nil
+	}
+
+	cp.store = p.cfg.checkpoints
+
+	seq, err := cp.store.Checkpoint(ctx, r.name)
+	if err != nil {
+		return nil, err
+	}
+
+	cp.saved = seq
+
+	return &cp, nil
+}
+
+// save stores the checkpoint even if ctx has already been canceled
+// so the progress made before the projector was stopped is not lost
+func (c *checkpointer) save(ctx context.Context, seq uint64) {
+	if c.store == nil || seq == c.saved {
+		return
+	}
+
+	err := c.store.SaveCheckpoint(context.WithoutCancel(ctx), c.name, seq)
+	if err != nil {
+		c.logErr(err)
+
+		return
+	}
+
+	c.saved = seq
 }
 
 func (p *Projector) logErr(err error) {
@@ -118,6 +779,9 @@ func (p *Projector) logErr(err error) {
 // FlushAfter wraps the projection passed in, and it calls
 // the projection itself as new events come (as usual) in addition to calling
 // the provided flush function periodically each time flush interval expires
+//
+// Deprecated: FlushAfter cannot be stopped, does not flush on shutdown and
+// reports flush errors only once the next event comes in. Use WithFlush instead
 func FlushAfter(
 	p Projection,
 	flush func() error,
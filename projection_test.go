@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
 )
 
 type streamer struct {
@@ -285,3 +286,122 @@ func TestShouldFlushProjection(t *testing.T) {
 		t.Fatal("flush should have been called")
 	}
 }
+
+func TestShouldProjectPartitionedPreservingStreamOrder(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	ctx := context.Background()
+
+	streams := []string{"stream-1", "stream-2", "stream-3", "stream-4"}
+
+	for i := 0; i < 5; i++ {
+		for _, stream := range streams {
+			err := es.AppendStream(ctx, stream, i, toEventToStore(SomeEvent{UserID: stream}))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var (
+		m   sync.Mutex
+		got = make(map[string][]int)
+	)
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed(
+		"partitioned",
+		func(_ context.Context, evt eventstore.StoredEvent) error {
+			m.Lock()
+			defer m.Unlock()
+
+			got[evt.StreamID] = append(got[evt.StreamID], evt.StreamVersion)
+
+			return nil
+		},
+		eventstore.WithPartitions(3),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+
+	defer cancel()
+
+	_ = p.Run(ctx)
+
+	m.Lock()
+	defer m.Unlock()
+
+	for _, stream := range streams {
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, got[stream])
+	}
+
+	seq, err := es.Checkpoint(context.Background(), "partitioned")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), seq)
+}
+
+func TestPartitionedProjectionShouldResumeFromLowestCompletedSequence(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for _, stream := range []string{"stream-1", "stream-2"} {
+		err := es.AppendStream(
+			ctx, stream, eventstore.InitialStreamVersion, toEventToStore(SomeEvent{}, SomeEvent{}, SomeEvent{}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		m      sync.Mutex
+		failed bool
+		got    = make(map[uint64]int)
+	)
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed(
+		"partitioned",
+		func(_ context.Context, evt eventstore.StoredEvent) error {
+			m.Lock()
+			defer m.Unlock()
+
+			if evt.Sequence == 2 && !failed {
+				failed = true
+
+				return fmt.Errorf("some transient error")
+			}
+
+			got[evt.Sequence]++
+
+			return nil
+		},
+		eventstore.WithPartitions(2),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+
+	defer cancel()
+
+	_ = p.Run(ctx)
+
+	m.Lock()
+	defer m.Unlock()
+
+	for seq := uint64(1); seq <= 6; seq++ {
+		assert.GreaterOrEqual(t, got[seq], 1, "event %d should have been projected", seq)
+	}
+
+	seq, err := es.Checkpoint(context.Background(), "partitioned")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), seq)
+}
@@ -0,0 +1,181 @@
+// Package prommetrics provides eventstore.Metrics implementation
+// backed by the prometheus client registry
+package prommetrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "eventstore"
+
+// Metrics implements eventstore.Metrics using prometheus collectors.
+// Streams are not used as labels in order to keep the cardinality low
+type Metrics struct {
+	appendedEvents  prometheus.Counter
+	appendedBytes   prometheus.Counter
+	appendDuration  *prometheus.HistogramVec
+	conflicts       prometheus.Counter
+	readDuration    *prometheus.HistogramVec
+	readEvents      prometheus.Histogram
+	pollDuration    *prometheus.HistogramVec
+	pollBatchSize   prometheus.Histogram
+	projected       *prometheus.CounterVec
+	projectedFailed *prometheus.CounterVec
+	projectionLag   *prometheus.GaugeVec
+}
+
+// New creates Metrics and registers its collectors with the registerer
+// (eg. prometheus.DefaultRegisterer)
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	batchBuckets := prometheus.ExponentialBuckets(1, 2, 11)
+
+	m := Metrics{
+		appendedEvents: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "appended_events_total",
+			Help:      "Number of events appended to the event store.",
+		}),
+		appendedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "appended_bytes_total",
+			Help:      "Number of encoded event (data and meta) bytes appended to the event store.",
+		}),
+		appendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "append_duration_seconds",
+			Help:      "Duration of stream appends.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		conflicts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "concurrency_conflicts_total",
+			Help:      "Number of appends which failed the optimistic concurrency check.",
+		}),
+		readDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "stream_read_duration_seconds",
+			Help:      "Duration of stream reads.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		readEvents: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "stream_read_events",
+			Help:      "Number of events read per stream read.",
+			Buckets:   batchBuckets,
+		}),
+		pollDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "subscription_poll_duration_seconds",
+			Help:      "Duration of subscription polls.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		pollBatchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "subscription_batch_size",
+			Help:      "Number of events read per subscription poll.",
+			Buckets:   batchBuckets,
+		}),
+		projected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "projection_processed_total",
+			Help:      "Number of events handled by a projection.",
+		}, []string{"projection"}),
+		projectedFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "projection_failed_total",
+			Help:      "Number of events a projection failed to handle.",
+		}, []string{"projection"}),
+		projectionLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "projection_lag_events",
+			Help:      "Number of events a projection is behind the event store.",
+		}, []string{"projection"}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.appendedEvents,
+		m.appendedBytes,
+		m.appendDuration,
+		m.conflicts,
+		m.readDuration,
+		m.readEvents,
+		m.pollDuration,
+		m.pollBatchSize,
+		m.projected,
+		m.projectedFailed,
+		m.projectionLag,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return &m, nil
+}
+
+// Appended records an append
+func (m *Metrics) Appended(_ string, events, bytes int, took time.Duration, err error) {
+	m.appendDuration.WithLabelValues(result(err)).Observe(took.Seconds())
+
+	if errors.Is(err, eventstore.ErrConcurrencyCheckFailed) {
+		m.conflicts.Inc()
+	}
+
+	if err != nil {
+		return
+	}
+
+	m.appendedEvents.Add(float64(events))
+	m.appendedBytes.Add(float64(bytes))
+}
+
+// StreamRead records a stream read
+func (m *Metrics) StreamRead(_ string, events int, took time.Duration, err error) {
+	m.readDuration.WithLabelValues(result(err)).Observe(took.Seconds())
+
+	if err == nil {
+		m.readEvents.Observe(float64(events))
+	}
+}
+
+// Polled records a subscription poll
+func (m *Metrics) Polled(events int, took time.Duration, err error) {
+	m.pollDuration.WithLabelValues(result(err)).Observe(took.Seconds())
+
+	if err == nil {
+		m.pollBatchSize.Observe(float64(events))
+	}
+}
+
+// Projected records an event handled by a projection
+func (m *Metrics) Projected(projection string, err error) {
+	if err != nil {
+		m.projectedFailed.WithLabelValues(projection).Inc()
+
+		return
+	}
+
+	m.projected.WithLabelValues(projection).Inc()
+}
+
+// ProjectionLag records the lag of a projection
+func (m *Metrics) ProjectionLag(projection string, lag uint64) {
+	m.projectionLag.WithLabelValues(projection).Set(float64(lag))
+}
+
+func result(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+
+	case errors.Is(err, eventstore.ErrConcurrencyCheckFailed):
+		return "conflict"
+
+	default:
+		return "error"
+	}
+}
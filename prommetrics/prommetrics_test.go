@@ -0,0 +1,94 @@
+package prommetrics_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/prommetrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShould_Record_Appends(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+
+	m, err := prommetrics.New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Appended("stream", 3, 120, time.Millisecond, nil)
+	m.Appended("stream", 1, 40, time.Millisecond, fmt.Errorf("append: %w", eventstore.ErrConcurrencyCheckFailed))
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP eventstore_appended_bytes_total Number of encoded event (data and meta) bytes appended to the event store.
+# TYPE eventstore_appended_bytes_total counter
+eventstore_appended_bytes_total 120
+# HELP eventstore_appended_events_total Number of events appended to the event store.
+# TYPE eventstore_appended_events_total counter
+eventstore_appended_events_total 3
+# HELP eventstore_concurrency_conflicts_total Number of appends which failed the optimistic concurrency check.
+# TYPE eventstore_concurrency_conflicts_total counter
+eventstore_concurrency_conflicts_total 1
+`),
+		"eventstore_appended_bytes_total",
+		"eventstore_appended_events_total",
+		"eventstore_concurrency_conflicts_total",
+	)
+
+	assert.NoError(t, err)
+
+	n, err := testutil.GatherAndCount(reg, "eventstore_append_duration_seconds")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestShould_Record_Projections(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+
+	m, err := prommetrics.New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Projected("users", nil)
+	m.Projected("users", nil)
+	m.Projected("users", fmt.Errorf("some error"))
+	m.ProjectionLag("users", 5)
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP eventstore_projection_failed_total Number of events a projection failed to handle.
+# TYPE eventstore_projection_failed_total counter
+eventstore_projection_failed_total{projection="users"} 1
+# HELP eventstore_projection_lag_events Number of events a projection is behind the event store.
+# TYPE eventstore_projection_lag_events gauge
+eventstore_projection_lag_events{projection="users"} 5
+# HELP eventstore_projection_processed_total Number of events handled by a projection.
+# TYPE eventstore_projection_processed_total counter
+eventstore_projection_processed_total{projection="users"} 2
+`),
+		"eventstore_projection_failed_total",
+		"eventstore_projection_lag_events",
+		"eventstore_projection_processed_total",
+	)
+
+	assert.NoError(t, err)
+}
+
+func TestShould_Fail_Registering_Twice(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+
+	_, err := prommetrics.New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = prommetrics.New(reg)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,327 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrProjectionNotFound indicates that there is no named projection
+	// registered with the projector under the given name
+	ErrProjectionNotFound = errors.New("projection not found")
+
+	// ErrRebuildInProgress indicates that the projection is already being rebuilt
+	ErrRebuildInProgress = errors.New("projection rebuild already in progress")
+)
+
+// shadowSuffix is appended to the projection name in order to
+// checkpoint the progress of its shadow copy
+const shadowSuffix = ".shadow"
+
+// Shadow can be implemented by projections whose read model can be rebuilt
+// into a separate (shadow) copy while the current one keeps serving
+type Shadow interface {
+	// Prepare creates a fresh (empty) shadow copy of the read model and returns
+	// the projection which writes to it
+	Prepare(ctx context.Context) (ProjectionFunc, error)
+
+	// Switch makes the shadow copy the live read model
+	Switch(ctx context.Context) error
+}
+
+// ShadowConfigurer can be implemented by a Shadow in order to configure the projection
+// writing to the shadow copy (eg. WithFlush flushing the shadow copy). ShadowOpts is called
+// after Prepare, so the options can refer to the prepared copy. The shadow projection
+// inherits partitions and subscription options of the projection, but it is not flushed,
+// reset or run within a transaction unless configured this way.
+// Once switched to, the projection is flushed using the flush of the shadow projection
+type ShadowConfigurer interface {
+	ShadowOpts() []ProjectionOpt
+}
+
+// WithReset is a projection option that sets a hook which is called
+// in order to clear the read model when the projection is rebuilt in place
+func WithReset(reset func(ctx context.Context) error) ProjectionOpt {
+	return func(cfg ProjectionCfg) ProjectionCfg {
+		cfg.reset = reset
+
+		return cfg
+	}
+}
+
+// WithShadow is a projection option that makes the projection rebuild
+// into a shadow copy of its read model (see Shadow) instead of in place
+func WithShadow(s Shadow) ProjectionOpt {
+	return func(cfg ProjectionCfg) ProjectionCfg {
+		cfg.shadow = s
+
+		return cfg
+	}
+}
+
+// Rebuild replays all events from the beginning of the event store into the named projection.
+// By default, the projection is rebuilt in place: it is stopped, its checkpoint is reset,
+// reset hook is called (see WithReset) and it is started again.
+// If the projection was registered WithShadow, events are replayed into the shadow copy
+// while the projection itself keeps running. Once the shadow copy has caught up, the
+// projection is briefly paused, the shadow copy is switched to, and the projection
+// continues from where the shadow copy left off.
+// If the projector is configured WithLocker, the lease of the projection is held while
+// the projection is being reset, so Rebuild waits for the instance running the projection
+// to give it up (call Rebuild on the instance running the projection or bound ctx).
+// Rebuild blocks until the rebuild is done and can be called regardless of
+// whether the projector is running or not
+func (p *Projector) Rebuild(ctx context.Context, name string) error {
+	if p.cfg.checkpoints == nil {
+		return fmt.Errorf("checkpoint store is required in order to rebuild projections")
+	}
+
+	p.mu.Lock()
+
+	r, ok := p.registration(name)
+	if !ok {
+		p.mu.Unlock()
+
+		return ErrProjectionNotFound
+	}
+
+	if p.rebuilding[name] {
+		p.mu.Unlock()
+
+		return ErrRebuildInProgress
+	}
+
+	if p.rebuilding == nil {
+		p.rebuilding = make(map[string]bool)
+	}
+
+	p.rebuilding[name] = true
+
+	// Make sure Run doesn't return while the projection is paused
+	p.wg.Add(1)
+
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.rebuilding, name)
+		p.mu.Unlock()
+
+		p.wg.Done()
+	}()
+
+	if r.cfg.shadow != nil {
+		return p.rebuildShadow(ctx, r)
+	}
+
+	rn := p.pause(name)
+
+	err := p.reset(ctx, r)
+
+	p.resume(rn, r)
+
+	return err
+}
+
+// reset resets the checkpoint of the (paused) projection and calls its reset hook
+// while holding the projection lease (if the projector is configured WithLocker)
+func (p *Projector) reset(ctx context.Context, r registration) error {
+	if p.cfg.locker != nil {
+		lease, err := p.cfg.locker.Acquire(ctx, r.name)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := lease.Release(context.WithoutCancel(ctx)); err != nil {
+				p.logErr(err)
+			}
+		}()
+	}
+
+	err := p.cfg.checkpoints.SaveCheckpoint(ctx, r.name, 0)
+	if err == nil && r.cfg.reset != nil {
+		err = r.cfg.reset(ctx)
+	}
+
+	return err
+}
+
+func (p *Projector) rebuildShadow(ctx context.Context, r registration) error {
+	project, err := r.cfg.shadow.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	shadowName := r.name + shadowSuffix
+
+	cfg := shadowCfg(r)
+
+	if r.cfg.flush != nil && cfg.flush == nil {
+		return fmt.Errorf("shadow of flushing projection %s needs to be flushed (see ShadowConfigurer)", r.name)
+	}
+
+	err = p.validate(registration{name: shadowName, cfg: cfg})
+	if err != nil {
+		return err
+	}
+
+	err = p.cfg.checkpoints.SaveCheckpoint(ctx, shadowName, 0)
+	if err != nil {
+		return err
+	}
+
+	caughtUp := make(chan struct{}, 1)
+
+	shadow := registration{
+		name:    shadowName,
+		project: project,
+		cfg:     cfg,
+		stats:   p.statsFor(shadowName),
+		metrics: p.cfg.metrics,
+		caughtUp: func() {
+			select {
+			case caughtUp <- struct{}{}:
+			default:
+			}
+		},
+	}
+
+	// The shadow is only reported while it is being rebuilt
+	defer p.dropStats(shadowName)
+
+	sctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		p.runProjection(sctx, shadow)
+	}()
+
+	stopShadow := func() {
+		cancel()
+		<-done
+	}
+
+	waitCaughtUp := func() error {
+		select {
+		case <-caughtUp:
+			return nil
+
+		case <-done:
+			return fmt.Errorf("shadow projection %s stopped before catching up", shadowName)
+
+		case <-ctx.Done():
+			stopShadow()
+
+			return ctx.Err()
+		}
+	}
+
+	if err := waitCaughtUp(); err != nil {
+		return err
+	}
+
+	rn := p.pause(r.name)
+
+	// Shadow needs to catch up with the events which might
+	// have been appended while it was catching up the first time
+	select {
+	case <-caughtUp:
+	default:
+	}
+
+	if err := waitCaughtUp(); err != nil {
+		p.resume(rn, r)
+
+		return err
+	}
+
+	stopShadow()
+
+	if err := r.cfg.shadow.Switch(ctx); err != nil {
+		p.resume(rn, r)
+
+		return err
+	}
+
+	seq, err := p.cfg.checkpoints.Checkpoint(ctx, shadowName)
+	if err == nil {
+		err = p.cfg.checkpoints.SaveCheckpoint(ctx, r.name, seq)
+	}
+
+	r.project = project
+
+	if cfg.flush != nil {
+		r.cfg.flush = cfg.flush
+	}
+
+	p.mu.Lock()
+
+	for i := range p.projections {
+		if p.projections[i].name == r.name {
+			p.projections[i].project = r.project
+			p.projections[i].cfg.flush = r.cfg.flush
+		}
+	}
+
+	p.mu.Unlock()
+
+	p.resume(rn, r)
+
+	return err
+}
+
+// shadowCfg returns the configuration of the projection writing to the shadow copy
+func shadowCfg(r registration) ProjectionCfg {
+	cfg := ProjectionCfg{
+		partitions: r.cfg.partitions,
+		subOpts:    r.cfg.subOpts,
+	}
+
+	if sc, ok := r.cfg.shadow.(ShadowConfigurer); ok {
+		for _, opt := range sc.ShadowOpts() {
+			cfg = opt(cfg)
+		}
+	}
+
+	return cfg
+}
+
+func (p *Projector) registration(name string) (registration, bool) {
+	for _, r := range p.projections {
+		if r.name != "" && r.name == name {
+			return r, true
+		}
+	}
+
+	return registration{}, false
+}
+
+// pause stops the running projection (if any) and waits for it to exit
+func (p *Projector) pause(name string) *runner {
+	p.mu.Lock()
+	rn, ok := p.runners[name]
+	p.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	rn.cancel()
+	<-rn.done
+
+	return rn
+}
+
+// resume starts the previously paused projection
+// (unless the projector has been stopped meanwhile)
+func (p *Projector) resume(rn *runner, r registration) {
+	if rn == nil || rn.ctx.Err() != nil {
+		return
+	}
+
+	p.start(rn.ctx, r)
+}
@@ -0,0 +1,336 @@
+package eventstore_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+type readModel struct {
+	m     sync.Mutex
+	users []string
+}
+
+func (rm *readModel) project(_ context.Context, evt eventstore.StoredEvent) error {
+	rm.m.Lock()
+	defer rm.m.Unlock()
+
+	rm.users = append(rm.users, evt.Event.(SomeEvent).UserID)
+
+	return nil
+}
+
+func (rm *readModel) get() []string {
+	rm.m.Lock()
+	defer rm.m.Unlock()
+
+	return append([]string(nil), rm.users...)
+}
+
+type shadowModel struct {
+	m       sync.Mutex
+	live    *readModel
+	shadow  *readModel
+	project eventstore.ProjectionFunc
+}
+
+func (s *shadowModel) Prepare(_ context.Context) (eventstore.ProjectionFunc, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.shadow = &readModel{}
+
+	return s.shadow.project, nil
+}
+
+func (s *shadowModel) Switch(_ context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.live = s.shadow
+
+	return nil
+}
+
+func (s *shadowModel) current() *readModel {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.live
+}
+
+func TestShouldRebuildProjectionInPlace(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	err := es.AppendStream(
+		ctx, "stream", eventstore.InitialStreamVersion,
+		toEventToStore(SomeEvent{UserID: "user-1"}, SomeEvent{UserID: "user-2"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rm readModel
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed(
+		"users",
+		rm.project,
+		eventstore.WithReset(func(_ context.Context) error {
+			rm.m.Lock()
+			defer rm.m.Unlock()
+
+			rm.users = nil
+
+			return nil
+		}),
+	)
+
+	go func() { _ = p.Run(ctx) }()
+
+	want := []string{"user-1", "user-2"}
+
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(want, rm.get()) }, 2*time.Second, 50*time.Millisecond)
+
+	err = p.Rebuild(ctx, "users")
+
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(want, rm.get()) }, 2*time.Second, 50*time.Millisecond)
+}
+
+func TestShouldRebuildProjectionIntoShadowCopy(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	err := es.AppendStream(
+		ctx, "stream", eventstore.InitialStreamVersion,
+		toEventToStore(SomeEvent{UserID: "user-1"}, SomeEvent{UserID: "user-2"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := &readModel{}
+	sm := shadowModel{live: live}
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed("users", live.project, eventstore.WithShadow(&sm))
+
+	go func() { _ = p.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return len(live.get()) == 2 }, 2*time.Second, 50*time.Millisecond)
+
+	err = p.Rebuild(ctx, "users")
+
+	assert.NoError(t, err)
+
+	rebuilt := sm.current()
+
+	assert.NotSame(t, live, rebuilt)
+	assert.Equal(t, []string{"user-1", "user-2"}, rebuilt.get())
+
+	err = es.AppendStream(ctx, "stream", 2, toEventToStore(SomeEvent{UserID: "user-3"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"user-1", "user-2", "user-3"}
+
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(want, rebuilt.get()) }, 2*time.Second, 50*time.Millisecond)
+	assert.Equal(t, []string{"user-1", "user-2"}, live.get())
+}
+
+func TestRebuildShouldReportUnknownProjection(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	p := eventstore.NewProjector(es)
+
+	err := p.Rebuild(context.Background(), "unknown")
+
+	assert.ErrorIs(t, err, eventstore.ErrProjectionNotFound)
+}
+
+type bufferedShadow struct {
+	m      sync.Mutex
+	live   *bufferedModel
+	shadow *bufferedModel
+}
+
+func (s *bufferedShadow) Prepare(_ context.Context) (eventstore.ProjectionFunc, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.shadow = &bufferedModel{}
+
+	return s.shadow.project, nil
+}
+
+func (s *bufferedShadow) ShadowOpts() []eventstore.ProjectionOpt {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return []eventstore.ProjectionOpt{eventstore.WithFlush(s.shadow.flush, 10, 20*time.Millisecond)}
+}
+
+func (s *bufferedShadow) Switch(_ context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.live = s.shadow
+
+	return nil
+}
+
+func (s *bufferedShadow) current() *bufferedModel {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.live
+}
+
+func flattened(batches [][]string) []string {
+	return slices.Concat(batches...)
+}
+
+func TestShouldFlushShadowCopyWithItsOwnFlush(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	err := es.AppendStream(
+		ctx, "stream", eventstore.InitialStreamVersion,
+		toEventToStore(SomeEvent{UserID: "user-1"}, SomeEvent{UserID: "user-2"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := &bufferedModel{}
+	bs := bufferedShadow{live: live}
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed(
+		"users",
+		live.project,
+		eventstore.WithFlush(live.flush, 10, 20*time.Millisecond),
+		eventstore.WithShadow(&bs),
+	)
+
+	go func() { _ = p.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return len(flattened(live.get())) == 2 }, 2*time.Second, 50*time.Millisecond)
+
+	err = p.Rebuild(ctx, "users")
+
+	assert.NoError(t, err)
+
+	rebuilt := bs.current()
+
+	assert.NotSame(t, live, rebuilt)
+	assert.Equal(t, []string{"user-1", "user-2"}, flattened(rebuilt.get()))
+
+	err = es.AppendStream(ctx, "stream", 2, toEventToStore(SomeEvent{UserID: "user-3"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"user-1", "user-2", "user-3"}
+
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(want, flattened(rebuilt.get())) }, 2*time.Second, 50*time.Millisecond)
+	assert.Equal(t, []string{"user-1", "user-2"}, flattened(live.get()))
+}
+
+func TestShadowOfFlushingProjectionMustBeFlushed(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	live := &readModel{}
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed(
+		"users",
+		live.project,
+		eventstore.WithFlush(func(_ context.Context) error { return nil }, 10, time.Second),
+		eventstore.WithShadow(&shadowModel{live: live}),
+	)
+
+	err := p.Rebuild(context.Background(), "users")
+
+	assert.Error(t, err)
+}
+
+func TestRebuildInPlaceShouldWaitForProjectionLease(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	locker := newLocker(t, es, time.Second)
+
+	// Another instance is running the projection
+	lease, err := locker.Acquire(context.Background(), "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = lease.Release(context.Background()) }()
+
+	err = es.SaveCheckpoint(context.Background(), "users", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reset := false
+
+	p := eventstore.NewProjector(es, eventstore.WithLocker(locker))
+
+	p.AddNamed(
+		"users",
+		func(_ context.Context, _ eventstore.StoredEvent) error { return nil },
+		eventstore.WithReset(func(_ context.Context) error {
+			reset = true
+
+			return nil
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+
+	defer cancel()
+
+	err = p.Rebuild(ctx, "users")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, reset)
+
+	seq, err := es.Checkpoint(context.Background(), "users")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), seq)
+}
@@ -0,0 +1,122 @@
+package eventstore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrEventNotFound indicates that the requested event does not exist in the event store
+var ErrEventNotFound = errors.New("event not found")
+
+// FromStart is a subscription / read all option that starts reading
+// from the beginning of the event store
+func FromStart() SubAllOpt {
+	return WithOffset(0)
+}
+
+// FromEnd is a subscription / read all option that starts reading from the current
+// end of the event store, so only events appended after the subscription was
+// created are read (eg. live notifications)
+func FromEnd() SubAllOpt {
+	return withStart(func(db *gorm.DB) (int, error) {
+		return headSequence(db)
+	})
+}
+
+// FromSequence is a subscription / read all option that starts reading
+// from the event with the given sequence (inclusive)
+func FromSequence(seq uint64) SubAllOpt {
+	return withStart(func(_ *gorm.DB) (int, error) {
+		if seq == 0 {
+			return 0, nil
+		}
+
+		return int(seq - 1), nil
+	})
+}
+
+// FromEventID is a subscription / read all option that starts reading
+// from the event with the given id (inclusive).
+// ErrEventNotFound is returned if there is no such event
+func FromEventID(id string) SubAllOpt {
+	return withStart(func(db *gorm.DB) (int, error) {
+		var evt gormEvent
+
+		err := db.
+			Select("sequence").
+			Where("id = ?", id).
+			First(&evt).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("%w: %s", ErrEventNotFound, id)
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		return int(evt.Sequence - 1), nil
+	})
+}
+
+// FromTime is a subscription / read all option that starts reading from the first
+// event which occurred on or after t. If there is no such event, reading starts
+// from the current end of the event store
+func FromTime(t time.Time) SubAllOpt {
+	return withStart(func(db *gorm.DB) (int, error) {
+		var seq *uint64
+
+		err := db.
+			Model(&gormEvent{}).
+			Select("MIN(sequence)").
+			Where("occurred_on >= ?", t.UTC()).
+			Scan(&seq).Error
+		if err != nil {
+			return 0, err
+		}
+
+		if seq == nil {
+			return headSequence(db)
+		}
+
+		return int(*seq - 1), nil
+	})
+}
+
+func headSequence(db *gorm.DB) (int, error) {
+	var seq uint64
+
+	err := db.
+		Model(&gormEvent{}).
+		Select("COALESCE(MAX(sequence), 0)").
+		Scan(&seq).Error
+
+	return int(seq), err
+}
+
+func withStart(start func(db *gorm.DB) (int, error)) SubAllOpt {
+	return func(cfg SubAllConfig) SubAllConfig {
+		cfg.start = start
+
+		return cfg
+	}
+}
+
+// resolveStart resolves the start position (if any) to the offset to read from
+func (es *EventStore) resolveStart(db *gorm.DB, cfg SubAllConfig) (SubAllConfig, error) {
+	if cfg.start == nil {
+		return cfg, nil
+	}
+
+	offset, err := cfg.start(db)
+	if err != nil {
+		return SubAllConfig{}, err
+	}
+
+	cfg.offset = offset
+	cfg.start = nil
+
+	return cfg, nil
+}
@@ -0,0 +1,146 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadAllStartPositions(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	past := time.Now().UTC().Add(-time.Hour)
+
+	err := es.AppendStream(context.Background(), "stream", eventstore.InitialStreamVersion, []eventstore.EventToStore{
+		{Event: SomeEvent{UserID: "user-1"}, ID: "event-1", OccurredOn: past.Add(-time.Hour)},
+		{Event: SomeEvent{UserID: "user-2"}, ID: "event-2", OccurredOn: past},
+		{Event: SomeEvent{UserID: "user-3"}, ID: "event-3", OccurredOn: past.Add(time.Minute)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		opt  eventstore.SubAllOpt
+		want []string
+	}{
+		{name: "start", opt: eventstore.FromStart(), want: []string{"user-1", "user-2", "user-3"}},
+		{name: "end", opt: eventstore.FromEnd(), want: nil},
+		{name: "sequence", opt: eventstore.FromSequence(2), want: []string{"user-2", "user-3"}},
+		{name: "event id", opt: eventstore.FromEventID("event-3"), want: []string{"user-3"}},
+		{name: "time", opt: eventstore.FromTime(past), want: []string{"user-2", "user-3"}},
+		{name: "future time", opt: eventstore.FromTime(time.Now().Add(time.Hour)), want: nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			evts, err := es.ReadAll(context.Background(), tc.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+
+			for _, evt := range evts {
+				ids = append(ids, evt.Event.(SomeEvent).UserID)
+			}
+
+			assert.Equal(t, tc.want, ids)
+		})
+	}
+}
+
+func TestReadAllFromTimeWithNonUTCOccurredOn(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	// Behind UTC, so local times of later events sort before the UTC times of earlier ones
+	zone := time.FixedZone("UTC-5", -5*60*60)
+
+	past := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	err := es.AppendStream(context.Background(), "stream", eventstore.InitialStreamVersion, []eventstore.EventToStore{
+		{Event: SomeEvent{UserID: "user-1"}, OccurredOn: past.Add(-time.Minute).In(zone)},
+		{Event: SomeEvent{UserID: "user-2"}, OccurredOn: past},
+		{Event: SomeEvent{UserID: "user-3"}, OccurredOn: past.Add(time.Minute).In(zone)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evts, err := es.ReadAll(context.Background(), eventstore.FromTime(past))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+
+	for _, evt := range evts {
+		ids = append(ids, evt.Event.(SomeEvent).UserID)
+
+		assert.Equal(t, time.UTC, evt.OccurredOn.Location())
+	}
+
+	assert.Equal(t, []string{"user-2", "user-3"}, ids)
+	assert.True(t, evts[1].OccurredOn.Equal(past.Add(time.Minute)))
+}
+
+func TestReadAllFromUnknownEventID(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	_, err := es.ReadAll(context.Background(), eventstore.FromEventID("foo"))
+
+	assert.ErrorIs(t, err, eventstore.ErrEventNotFound)
+}
+
+func TestSubscribeAllFromEndReadsOnlyNewEvents(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 3)
+
+	sub, err := es.SubscribeAll(
+		context.Background(),
+		eventstore.FromEnd(),
+		eventstore.WithPollInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sub.Close()
+
+	err = es.AppendStream(context.Background(), "other-stream", eventstore.InitialStreamVersion, toEventToStore(SomeEvent{UserID: "user-4"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evts := readAllSub(t, sub, 1)
+
+	assert.Equal(t, SomeEvent{UserID: "user-4"}, evts[0].Event)
+}
+
+func TestOffsetOverridesStartPosition(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 3)
+
+	evts, err := es.ReadAll(context.Background(), eventstore.FromEnd(), eventstore.WithOffset(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, evts, 1)
+}
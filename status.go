@@ -0,0 +1,295 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// throughputWindow is the period over which projection throughput is measured
+const throughputWindow = 10 * time.Second
+
+// HeadReader can be implemented by event streamers that are able to report
+// the sequence and occurrence time of the latest event in the store.
+// This package offers EventStore as HeadReader implementation
+type HeadReader interface {
+	Head(ctx context.Context) (uint64, time.Time, error)
+}
+
+// ProjectionState represents the state of a named projection
+type ProjectionState string
+
+const (
+	// ProjectionRunning indicates that the projection is handling events
+	ProjectionRunning ProjectionState = "running"
+
+	// ProjectionRetrying indicates that the projection has failed and is being restarted
+	ProjectionRetrying ProjectionState = "retrying"
+
+	// ProjectionStandby indicates that the projection is waiting to acquire
+	// its lease as it is being run by another projector instance (see WithLocker)
+	ProjectionStandby ProjectionState = "standby"
+
+	// ProjectionStopped indicates that the projection is not running
+	ProjectionStopped ProjectionState = "stopped"
+)
+
+// ProjectionStatus represents the status of a named projection
+// Head, Lag and TimeLag are only reported if the event streamer is a HeadReader
+type ProjectionStatus struct {
+	Name  string          `json:"name"`
+	State ProjectionState `json:"state"`
+
+	// Sequence is the sequence of the last processed event
+	Sequence uint64 `json:"sequence"`
+
+	// Head is the sequence of the latest event in the event store
+	Head uint64 `json:"head"`
+
+	// Lag is the number of events the projection is behind the event store
+	Lag uint64 `json:"lag"`
+
+	// TimeLag is the time between the occurrence of the last processed and latest event
+	TimeLag time.Duration `json:"time_lag_ns"`
+
+	// Throughput is the number of events processed per second
+	Throughput float64 `json:"throughput"`
+
+	Errors    int    `json:"errors"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Status reports the status of each named projection
+func (p *Projector) Status(ctx context.Context) ([]ProjectionStatus, error) {
+	var (
+		head       uint64
+		headOn     time.Time
+		reportHead bool
+	)
+
+	if hr, ok := p.streamer.(HeadReader); ok {
+		var err error
+
+		head, headOn, err = hr.Head(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		reportHead = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make([]ProjectionStatus, 0, len(p.statNames))
+
+	for _, name := range p.statNames {
+		status := p.stats[name].status(name)
+
+		if reportHead {
+			status.Head = head
+
+			if head > status.Sequence {
+				status.Lag = head - status.Sequence
+
+				if on := p.stats[name].lastOccurredOn(); !on.IsZero() && headOn.After(on) {
+					status.TimeLag = headOn.Sub(on)
+				}
+			}
+		}
+
+		out = append(out, status)
+	}
+
+	return out, nil
+}
+
+// StatusHandler returns http.Handler which responds with json encoded status of each named projection.
+// It responds with 503 (Service Unavailable) if any projection is stopped or if it falls behind the
+// event store by more than maxLag events or maxTimeLag, which makes it suitable for health checks.
+// Zero maxLag or maxTimeLag disable the respective check
+func (p *Projector) StatusHandler(maxLag uint64, maxTimeLag time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := p.Status(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		code := http.StatusOK
+
+		for _, s := range statuses {
+			if s.State == ProjectionStopped ||
+				(maxLag > 0 && s.Lag > maxLag) ||
+				(maxTimeLag > 0 && s.TimeLag > maxTimeLag) {
+				code = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+
+		_ = json.NewEncoder(w).Encode(statuses)
+	})
+}
+
+func (p *Projector) statsFor(name string) *stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stats == nil {
+		p.stats = make(map[string]*stats)
+	}
+
+	if s, ok := p.stats[name]; ok {
+		return s
+	}
+
+	s := stats{state: ProjectionStopped}
+
+	p.stats[name] = &s
+	p.statNames = append(p.statNames, name)
+
+	return &s
+}
+
+// dropStats stops reporting the status of the named projection
+// (eg. the shadow projection once the rebuild is done)
+func (p *Projector) dropStats(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.stats, name)
+
+	p.statNames = slices.DeleteFunc(p.statNames, func(n string) bool { return n == name })
+}
+
+// stats collects the status of a running projection
+// All methods are safe to be called on nil stats
+type stats struct {
+	mu sync.Mutex
+
+	state      ProjectionState
+	seq        uint64
+	occurredOn time.Time
+	errors     int
+	lastErr    error
+
+	windowStart time.Time
+	count       int
+	throughput  float64
+}
+
+func (s *stats) setState(state ProjectionState) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = state
+}
+
+func (s *stats) failed(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = ProjectionRetrying
+	s.errors++
+	s.lastErr = err
+}
+
+func (s *stats) started(seq uint64) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = ProjectionRunning
+
+	if seq != s.seq {
+		s.seq = seq
+		s.occurredOn = time.Time{}
+	}
+}
+
+func (s *stats) processed(seq uint64, occurredOn time.Time) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq = seq
+	s.occurredOn = occurredOn
+
+	now := time.Now()
+
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+	}
+
+	s.count++
+
+	if elapsed := now.Sub(s.windowStart); elapsed >= throughputWindow {
+		s.throughput = float64(s.count) / elapsed.Seconds()
+		s.windowStart = now
+		s.count = 0
+	}
+}
+
+// advance moves the sequence past the events skipped while reading
+func (s *stats) advance(seq uint64) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq = max(s.seq, seq)
+}
+
+func (s *stats) lastOccurredOn() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.occurredOn
+}
+
+func (s *stats) status(name string) ProjectionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := ProjectionStatus{
+		Name:       name,
+		State:      s.state,
+		Sequence:   s.seq,
+		Throughput: s.throughput,
+		Errors:     s.errors,
+	}
+
+	if s.throughput == 0 && !s.windowStart.IsZero() {
+		if elapsed := time.Since(s.windowStart); elapsed > 0 {
+			status.Throughput = float64(s.count) / elapsed.Seconds()
+		}
+	}
+
+	if s.lastErr != nil {
+		status.LastError = s.lastErr.Error()
+	}
+
+	return status
+}
@@ -0,0 +1,164 @@
+package eventstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldReportProjectionStatus(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	err := es.AppendStream(
+		ctx, "stream", eventstore.InitialStreamVersion,
+		toEventToStore(SomeEvent{UserID: "user-1"}, SomeEvent{UserID: "user-2"}, SomeEvent{UserID: "user-3"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := false
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed("users", func(_ context.Context, evt eventstore.StoredEvent) error {
+		if evt.Sequence == 2 && !failed {
+			failed = true
+
+			return fmt.Errorf("some transient error")
+		}
+
+		return nil
+	})
+
+	p.AddNamed("stuck", func(_ context.Context, evt eventstore.StoredEvent) error {
+		if evt.Sequence == 2 {
+			<-ctx.Done()
+		}
+
+		return nil
+	})
+
+	go func() { _ = p.Run(ctx) }()
+
+	var statuses []eventstore.ProjectionStatus
+
+	assert.Eventually(t, func() bool {
+		statuses, err = p.Status(ctx)
+
+		return err == nil && statuses[0].Sequence == 3 && statuses[1].Sequence == 1
+	}, 2*time.Second, 50*time.Millisecond)
+
+	users := statuses[0]
+
+	assert.Equal(t, "users", users.Name)
+	assert.Equal(t, eventstore.ProjectionRunning, users.State)
+	assert.Equal(t, uint64(3), users.Head)
+	assert.Equal(t, uint64(0), users.Lag)
+	assert.Equal(t, 1, users.Errors)
+	assert.Equal(t, "some transient error", users.LastError)
+
+	stuck := statuses[1]
+
+	assert.Equal(t, "stuck", stuck.Name)
+	assert.Equal(t, uint64(2), stuck.Lag)
+	assert.Equal(t, 0, stuck.Errors)
+
+	rec := httptest.NewRecorder()
+
+	p.StatusHandler(1, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var got []eventstore.ProjectionStatus
+
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Len(t, got, 2)
+
+	rec = httptest.NewRecorder()
+
+	p.StatusHandler(2, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestShouldReportStoppedProjections(t *testing.T) {
+	p := eventstore.NewProjector(streamer{})
+
+	p.AddNamed("users", func(_ context.Context, _ eventstore.StoredEvent) error { return nil })
+
+	_ = p.Run(context.Background())
+
+	statuses, err := p.Status(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, eventstore.ProjectionStopped, statuses[0].State)
+
+	rec := httptest.NewRecorder()
+
+	p.StatusHandler(0, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestShouldNotReportShadowProjectionAfterRebuild(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	err := es.AppendStream(
+		ctx, "stream", eventstore.InitialStreamVersion,
+		toEventToStore(SomeEvent{UserID: "user-1"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := &readModel{}
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed("users", live.project, eventstore.WithShadow(&shadowModel{live: live}))
+
+	go func() { _ = p.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return len(live.get()) == 1 }, 2*time.Second, 50*time.Millisecond)
+
+	err = p.Rebuild(ctx, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+
+	assert.Eventually(t, func() bool {
+		rec = httptest.NewRecorder()
+
+		p.StatusHandler(0, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+		return rec.Code == http.StatusOK
+	}, 2*time.Second, 50*time.Millisecond)
+
+	var statuses []eventstore.ProjectionStatus
+
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&statuses))
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, "users", statuses[0].Name)
+}
@@ -0,0 +1,493 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	groupEventPending  = "pending"
+	groupEventInFlight = "in_flight"
+	groupEventParked   = "parked"
+)
+
+// ErrEventNotLeased is returned when acking or nacking an event which is not (anymore)
+// leased by the consumer, eg. because its ack timeout expired and it was fetched by another consumer
+var ErrEventNotLeased = errors.New("event is not leased by the consumer")
+
+// GroupCfg (configure using GroupOpt)
+type GroupCfg struct {
+	ackTimeout   time.Duration
+	maxRetries   int
+	batchSize    int
+	pollInterval time.Duration
+	logger       *log.Logger
+}
+
+// GroupOpt represents subscription group option
+type GroupOpt func(GroupCfg) GroupCfg
+
+// WithAckTimeout is a subscription group option that specifies for how long
+// an event fetched by a consumer stays in flight. If not acked or nacked within
+// the timeout, the event is redelivered to the next consumer fetching events
+func WithAckTimeout(d time.Duration) GroupOpt {
+	return func(cfg GroupCfg) GroupCfg {
+		cfg.ackTimeout = d
+
+		return cfg
+	}
+}
+
+// WithMaxRetries is a subscription group option that specifies how many times
+// an event is redelivered before it is parked (see SubscriptionGroup.Parked)
+func WithMaxRetries(n int) GroupOpt {
+	return func(cfg GroupCfg) GroupCfg {
+		cfg.maxRetries = n
+
+		return cfg
+	}
+}
+
+// WithGroupBatchSize is a subscription group option that specifies the
+// number of events fetched by Consume at once
+func WithGroupBatchSize(size int) GroupOpt {
+	return func(cfg GroupCfg) GroupCfg {
+		cfg.batchSize = size
+
+		return cfg
+	}
+}
+
+// WithGroupPollInterval is a subscription group option that specifies the
+// interval at which Consume polls for new events once there are none left
+func WithGroupPollInterval(d time.Duration) GroupOpt {
+	return func(cfg GroupCfg) GroupCfg {
+		cfg.pollInterval = d
+
+		return cfg
+	}
+}
+
+// WithGroupLogger is a subscription group option that sets the logger
+// Consume logs errors with (log.Default() by default)
+func WithGroupLogger(l *log.Logger) GroupOpt {
+	return func(cfg GroupCfg) GroupCfg {
+		cfg.logger = l
+
+		return cfg
+	}
+}
+
+type gormGroup struct {
+	Name         string `gorm:"primaryKey"`
+	LastSequence uint64
+}
+
+// TableName returns gorm table name
+func (gg *gormGroup) TableName() string { return "subscription_group" }
+
+type gormGroupEvent struct {
+	GroupName   string `gorm:"primaryKey;index:subscription_group_event_idx_state,priority:1"`
+	Sequence    uint64 `gorm:"primaryKey;autoIncrement:false"`
+	State       string `gorm:"index:subscription_group_event_idx_state,priority:2"`
+	Attempts    int
+	Consumer    string
+	LeasedUntil time.Time
+	Error       string
+}
+
+// TableName returns gorm table name
+func (ge *gormGroupEvent) TableName() string { return "subscription_group_event" }
+
+// GroupEvent represents an event delivered to a subscription group consumer
+type GroupEvent struct {
+	StoredEvent
+
+	// Attempt is the delivery attempt of the event (starting from 1)
+	Attempt int
+
+	// Error is set for parked events which could not be decoded
+	// (the event is returned undecoded, see DecodeRaw)
+	Error string
+}
+
+// SubscriptionGroup is a persistent subscription to all events, shared by any number
+// of competing consumers, each of which receives a portion of the events.
+// State of the group is stored in the event store database. Each fetched event
+// needs to be either acked or nacked. Events which are nacked or not acked in time
+// (see WithAckTimeout) are redelivered and eventually parked (see WithMaxRetries).
+// Unlike with Projector, the order of events is not preserved across consumers
+type SubscriptionGroup struct {
+	es   *EventStore
+	name string
+	cfg  GroupCfg
+}
+
+// SubscriptionGroup creates (if it does not exist) and returns the named subscription group.
+// Newly created group starts from the beginning of the event store
+func (es *EventStore) SubscriptionGroup(ctx context.Context, name string, opts ...GroupOpt) (*SubscriptionGroup, error) {
+	if len(name) == 0 {
+		return nil, fmt.Errorf("subscription group name must be provided")
+	}
+
+	cfg := GroupCfg{
+		ackTimeout:   30 * time.Second,
+		maxRetries:   5,
+		batchSize:    100,
+		pollInterval: 100 * time.Millisecond,
+		logger:       log.Default(),
+	}
+
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+
+	if cfg.batchSize < 1 {
+		return nil, fmt.Errorf("batch size should be at least 1")
+	}
+
+	err := es.conn(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&gormGroup{Name: name}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &SubscriptionGroup{
+		es:   es,
+		name: name,
+		cfg:  cfg,
+	}, nil
+}
+
+// Fetch claims up to n available events for the consumer
+func (g *SubscriptionGroup) Fetch(ctx context.Context, consumer string, n int) ([]GroupEvent, error) {
+	var claimed []gormGroupEvent
+
+	err := g.es.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := g.pull(tx, max(n, g.cfg.batchSize)); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+
+		if err := tx.
+			Model(&gormGroupEvent{}).
+			Where(
+				"group_name = ? AND state = ? AND leased_until < ? AND attempts > ?",
+				g.name, groupEventInFlight, now, g.cfg.maxRetries,
+			).
+			Update("state", groupEventParked).Error; err != nil {
+			return err
+		}
+
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where(
+				"group_name = ? AND (state = ? OR (state = ? AND leased_until < ?))",
+				g.name, groupEventPending, groupEventInFlight, now,
+			).
+			Order("sequence asc").
+			Limit(n).
+			Find(&claimed).Error; err != nil {
+			return err
+		}
+
+		if len(claimed) == 0 {
+			return nil
+		}
+
+		return tx.
+			Model(&gormGroupEvent{}).
+			Where("group_name = ? AND sequence IN ?", g.name, sequences(claimed)).
+			Updates(map[string]any{
+				"state":        groupEventInFlight,
+				"consumer":     consumer,
+				"leased_until": now.Add(g.cfg.ackTimeout),
+				"attempts":     gorm.Expr("attempts + 1"),
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return g.events(ctx, claimed, 1)
+}
+
+// pull moves up to limit new events from the event store to the group (as pending)
+// unless the group already has enough pending events
+func (g *SubscriptionGroup) pull(tx *gorm.DB, limit int) error {
+	var group gormGroup
+
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("name = ?", g.name).
+		First(&group).Error; err != nil {
+		return err
+	}
+
+	var pending int64
+
+	if err := tx.
+		Model(&gormGroupEvent{}).
+		Where("group_name = ? AND state = ?", g.name, groupEventPending).
+		Count(&pending).Error; err != nil {
+		return err
+	}
+
+	if pending >= int64(limit) {
+		return nil
+	}
+
+	var seqs []uint64
+
+	if err := tx.
+		Model(&gormEvent{}).
+		Where("sequence > ?", group.LastSequence).
+		Order("sequence asc").
+		Limit(limit).
+		Pluck("sequence", &seqs).Error; err != nil {
+		return err
+	}
+
+	if len(seqs) == 0 {
+		return nil
+	}
+
+	evts := make([]gormGroupEvent, len(seqs))
+
+	for i, seq := range seqs {
+		evts[i] = gormGroupEvent{
+			GroupName: g.name,
+			Sequence:  seq,
+			State:     groupEventPending,
+		}
+	}
+
+	if err := tx.Create(&evts).Error; err != nil {
+		return err
+	}
+
+	return tx.
+		Model(&gormGroup{}).
+		Where("name = ?", g.name).
+		Update("last_sequence", seqs[len(seqs)-1]).Error
+}
+
+// events loads and decodes stored events for the group events
+// (attemptOffset is added to the stored number of attempts).
+// Each event is decoded separately, fetched events which cannot be decoded are
+// parked with the decoding error and undecodable parked events are returned undecoded
+func (g *SubscriptionGroup) events(ctx context.Context, ges []gormGroupEvent, attemptOffset int) ([]GroupEvent, error) {
+	if len(ges) == 0 {
+		return nil, nil
+	}
+
+	var evts []gormEvent
+
+	if err := g.es.conn(ctx).
+		Where("sequence IN ?", sequences(ges)).
+		Order("sequence asc").
+		Find(&evts).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make(map[uint64]gormGroupEvent, len(ges))
+
+	for _, ge := range ges {
+		rows[ge.Sequence] = ge
+	}
+
+	var (
+		out     []GroupEvent
+		skipped []uint64
+	)
+
+	for _, evt := range evts {
+		ge := rows[evt.Sequence]
+
+		decoded, ok, err := g.es.decodeEvent(evt, g.es.decodePolicy)
+		if err != nil {
+			if ge.State == groupEventParked {
+				raw := rawEvent(evt)
+				raw.Event = raw.Raw
+
+				out = append(out, GroupEvent{StoredEvent: raw, Attempt: ge.Attempts + attemptOffset, Error: ge.Error})
+
+				continue
+			}
+
+			g.logErr(err)
+
+			if err := g.park(ctx, evt.Sequence, err); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if !ok {
+			skipped = append(skipped, evt.Sequence)
+
+			continue
+		}
+
+		out = append(out, GroupEvent{StoredEvent: decoded, Attempt: ge.Attempts + attemptOffset, Error: ge.Error})
+	}
+
+	if err := g.ackSkipped(ctx, skipped); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// park parks the fetched event which could not be decoded, recording the error
+func (g *SubscriptionGroup) park(ctx context.Context, seq uint64, err error) error {
+	return g.es.conn(ctx).
+		Model(&gormGroupEvent{}).
+		Where("group_name = ? AND sequence = ? AND state = ?", g.name, seq, groupEventInFlight).
+		Updates(map[string]any{
+			"state": groupEventParked,
+			"error": err.Error(),
+		}).Error
+}
+
+// ackSkipped acks the fetched events which were skipped while decoding (see DecodeSkip)
+// Parked events are left as they are
+func (g *SubscriptionGroup) ackSkipped(ctx context.Context, skipped []uint64) error {
+	if len(skipped) == 0 {
+		return nil
+	}
+
+	return g.es.conn(ctx).
+		Where("group_name = ? AND sequence IN ? AND state = ?", g.name, skipped, groupEventInFlight).
+		Delete(&gormGroupEvent{}).Error
+}
+
+// Ack acknowledges that the event fetched by the consumer has been handled
+// (ErrEventNotLeased is returned if the event is not leased by the consumer)
+func (g *SubscriptionGroup) Ack(ctx context.Context, consumer string, seq uint64) error {
+	tx := g.leased(ctx, consumer, seq).Delete(&gormGroupEvent{})
+
+	return leaseResult(tx)
+}
+
+// Nack indicates that the event fetched by the consumer could not be handled. The event is
+// redelivered unless it has exhausted its retries, in which case it is parked
+// (ErrEventNotLeased is returned if the event is not leased by the consumer)
+func (g *SubscriptionGroup) Nack(ctx context.Context, consumer string, seq uint64) error {
+	tx := g.leased(ctx, consumer, seq).
+		Where("attempts > ?", g.cfg.maxRetries).
+		Update("state", groupEventParked)
+	if tx.Error != nil || tx.RowsAffected == 1 {
+		return tx.Error
+	}
+
+	tx = g.leased(ctx, consumer, seq).Update("state", groupEventPending)
+
+	return leaseResult(tx)
+}
+
+// leased scopes the query to the event if it is in flight and leased by the consumer
+func (g *SubscriptionGroup) leased(ctx context.Context, consumer string, seq uint64) *gorm.DB {
+	return g.es.conn(ctx).
+		Model(&gormGroupEvent{}).
+		Where(
+			"group_name = ? AND sequence = ? AND state = ? AND consumer = ?",
+			g.name, seq, groupEventInFlight, consumer,
+		)
+}
+
+func leaseResult(tx *gorm.DB) error {
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return ErrEventNotLeased
+	}
+
+	return nil
+}
+
+// Parked returns events which have exhausted their retries
+func (g *SubscriptionGroup) Parked(ctx context.Context) ([]GroupEvent, error) {
+	var parked []gormGroupEvent
+
+	if err := g.es.conn(ctx).
+		Where("group_name = ? AND state = ?", g.name, groupEventParked).
+		Order("sequence asc").
+		Find(&parked).Error; err != nil {
+		return nil, err
+	}
+
+	return g.events(ctx, parked, 0)
+}
+
+// Replay makes the parked event available for delivery again (with retries reset)
+func (g *SubscriptionGroup) Replay(ctx context.Context, seq uint64) error {
+	return g.es.conn(ctx).
+		Model(&gormGroupEvent{}).
+		Where("group_name = ? AND sequence = ? AND state = ?", g.name, seq, groupEventParked).
+		Updates(map[string]any{
+			"state":    groupEventPending,
+			"attempts": 0,
+			"error":    "",
+		}).Error
+}
+
+// Consume fetches events for the consumer and passes them to the handler until ctx is done.
+// Events are acked if the handler succeeds, otherwise they are nacked.
+// Errors fetching or acking events are logged and fetching is retried after the poll interval
+func (g *SubscriptionGroup) Consume(ctx context.Context, consumer string, handler func(context.Context, GroupEvent) error) {
+	for {
+		evts, err := g.Fetch(ctx, consumer, g.cfg.batchSize)
+		if err != nil && ctx.Err() == nil {
+			g.logErr(err)
+		}
+
+		for _, evt := range evts {
+			ack := g.Ack
+
+			if err := handler(ctx, evt); err != nil {
+				ack = g.Nack
+			}
+
+			if err := ack(context.WithoutCancel(ctx), consumer, evt.Sequence); err != nil {
+				g.logErr(err)
+			}
+		}
+
+		if len(evts) != 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-time.After(g.cfg.pollInterval):
+		}
+	}
+}
+
+func (g *SubscriptionGroup) logErr(err error) {
+	g.cfg.logger.Printf("subscription group %s error: %v", g.name, err)
+}
+
+func sequences(ges []gormGroupEvent) []uint64 {
+	seqs := make([]uint64, len(ges))
+
+	for i, ge := range ges {
+		seqs[i] = ge.Sequence
+	}
+
+	return seqs
+}
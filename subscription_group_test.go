@@ -0,0 +1,255 @@
+package eventstore_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func appendUsers(t *testing.T, es *eventstore.EventStore, n int) {
+	t.Helper()
+
+	var evts []any
+
+	for i := 1; i <= n; i++ {
+		evts = append(evts, SomeEvent{UserID: fmt.Sprintf("user-%d", i)})
+	}
+
+	err := es.AppendStream(context.Background(), "stream", eventstore.InitialStreamVersion, toEventToStore(evts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubscriptionGroupShouldShareEventsBetweenConsumers(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 10)
+
+	ctx := context.Background()
+
+	group, err := es.SubscriptionGroup(ctx, "emails", eventstore.WithGroupBatchSize(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := group.Fetch(ctx, "consumer-1", 4)
+
+	assert.NoError(t, err)
+	assert.Len(t, first, 4)
+
+	second, err := group.Fetch(ctx, "consumer-2", 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, second, 6)
+
+	for _, evt := range first {
+		assert.Equal(t, 1, evt.Attempt)
+		assert.NoError(t, group.Ack(ctx, "consumer-1", evt.Sequence))
+	}
+
+	for _, evt := range second {
+		assert.Equal(t, 1, evt.Attempt)
+		assert.NoError(t, group.Ack(ctx, "consumer-2", evt.Sequence))
+	}
+
+	none, err := group.Fetch(ctx, "consumer-1", 10)
+
+	assert.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestSubscriptionGroupShouldRedeliverAndParkEvents(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 2)
+
+	ctx := context.Background()
+
+	group, err := es.SubscriptionGroup(
+		ctx,
+		"emails",
+		eventstore.WithMaxRetries(1),
+		eventstore.WithAckTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evts, err := group.Fetch(ctx, "consumer-1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, group.Nack(ctx, "consumer-1", evts[0].Sequence))
+
+	// second event times out
+	time.Sleep(150 * time.Millisecond)
+
+	evts, err = group.Fetch(ctx, "consumer-2", 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, evts, 2)
+	assert.Equal(t, 2, evts[0].Attempt)
+	assert.Equal(t, 2, evts[1].Attempt)
+
+	assert.NoError(t, group.Nack(ctx, "consumer-2", evts[0].Sequence))
+	assert.NoError(t, group.Nack(ctx, "consumer-2", evts[1].Sequence))
+
+	evts, err = group.Fetch(ctx, "consumer-2", 2)
+
+	assert.NoError(t, err)
+	assert.Empty(t, evts)
+
+	parked, err := group.Parked(ctx)
+
+	assert.NoError(t, err)
+	assert.Len(t, parked, 2)
+	assert.Equal(t, "user-1", parked[0].Event.(SomeEvent).UserID)
+
+	assert.NoError(t, group.Replay(ctx, parked[0].Sequence))
+
+	evts, err = group.Fetch(ctx, "consumer-2", 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, evts, 1)
+	assert.Equal(t, 1, evts[0].Attempt)
+}
+
+func TestSubscriptionGroupConsumersShouldHandleEachEventOnce(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	group, err := es.SubscriptionGroup(ctx, "emails", eventstore.WithGroupBatchSize(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		m   sync.Mutex
+		got = make(map[string]int)
+	)
+
+	for _, consumer := range []string{"consumer-1", "consumer-2"} {
+		go func(consumer string) {
+			group.Consume(ctx, consumer, func(_ context.Context, evt eventstore.GroupEvent) error {
+				m.Lock()
+				defer m.Unlock()
+
+				got[evt.Event.(SomeEvent).UserID]++
+
+				return nil
+			})
+		}(consumer)
+	}
+
+	assert.Eventually(t, func() bool {
+		m.Lock()
+		defer m.Unlock()
+
+		return len(got) == 20
+	}, 3*time.Second, 50*time.Millisecond)
+
+	m.Lock()
+	defer m.Unlock()
+
+	for user, n := range got {
+		assert.Equal(t, 1, n, "%s should have been handled once", user)
+	}
+}
+
+func TestSubscriptionGroupShouldRejectAcksOfStaleConsumers(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendUsers(t, es, 1)
+
+	ctx := context.Background()
+
+	group, err := es.SubscriptionGroup(ctx, "emails", eventstore.WithAckTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := group.Fetch(ctx, "consumer-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	evts, err := group.Fetch(ctx, "consumer-2", 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, evts, 1)
+
+	assert.ErrorIs(t, group.Nack(ctx, "consumer-1", stale[0].Sequence), eventstore.ErrEventNotLeased)
+	assert.ErrorIs(t, group.Ack(ctx, "consumer-1", stale[0].Sequence), eventstore.ErrEventNotLeased)
+
+	none, err := group.Fetch(ctx, "consumer-3", 1)
+
+	assert.NoError(t, err)
+	assert.Empty(t, none)
+
+	assert.NoError(t, group.Ack(ctx, "consumer-2", evts[0].Sequence))
+}
+
+func TestSubscriptionGroupShouldParkOnlyEventsWhichCannotBeDecoded(t *testing.T) {
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	appendPoisoned(t, es)
+
+	ctx := context.Background()
+
+	g, err := es.SubscriptionGroup(ctx, "group", eventstore.WithMaxRetries(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evts, err := g.Fetch(ctx, "consumer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, evts, 2)
+	assert.Equal(t, uint64(1), evts[0].Sequence)
+	assert.Equal(t, uint64(3), evts[1].Sequence)
+
+	for _, evt := range evts {
+		assert.NoError(t, g.Ack(ctx, "consumer", evt.Sequence))
+	}
+
+	parked, err := g.Parked(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, parked, 1)
+	assert.Equal(t, uint64(2), parked[0].Sequence)
+	assert.Contains(t, parked[0].Error, "poison event 2")
+	assert.Equal(t, eventstore.EncodedEvt{Type: "SomeEvent", Data: "malformed"}, parked[0].Event)
+
+	evts, err = g.Fetch(ctx, "consumer", 10)
+
+	assert.NoError(t, err)
+	assert.Empty(t, evts)
+}
@@ -0,0 +1,109 @@
+package eventstore
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/aneshas/eventstore/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the OpenTelemetry tracer used by the event store
+// (spans are recorded using the global tracer provider, see otel.SetTracerProvider)
+const TracerName = tracing.TracerName
+
+// Span attribute keys
+const (
+	AttrStream     = attribute.Key("eventstore.stream")
+	AttrVersion    = attribute.Key("eventstore.version")
+	AttrEventCount = attribute.Key("eventstore.event_count")
+	AttrSequence   = attribute.Key("eventstore.sequence")
+	AttrEventType  = attribute.Key("eventstore.event_type")
+	AttrProjection = attribute.Key("eventstore.projection")
+)
+
+var traceContext = propagation.TraceContext{}
+
+// WithTraceContext is an event store option that captures the trace context of the span
+// appending events in the meta of the appended events (see InjectTraceContext), so projections
+// continue the trace the events were appended in (see StartEventSpan). It is opt-in, since the
+// trace context is persisted with every event
+func WithTraceContext() Option {
+	return func(cfg Cfg) Cfg {
+		cfg.TraceContext = true
+
+		return cfg
+	}
+}
+
+// InjectTraceContext returns a copy of meta with the W3C trace context
+// (traceparent and tracestate) of the span carried by ctx added.
+// If ctx does not carry a valid span, meta is returned as is
+func InjectTraceContext(ctx context.Context, meta map[string]string) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return meta
+	}
+
+	out := make(map[string]string, len(meta)+2)
+
+	maps.Copy(out, meta)
+
+	traceContext.Inject(ctx, propagation.MapCarrier(out))
+
+	return out
+}
+
+// ExtractTraceContext returns a copy of ctx carrying the remote span
+// context captured in meta on append (see InjectTraceContext)
+func ExtractTraceContext(ctx context.Context, meta map[string]string) context.Context {
+	return traceContext.Extract(ctx, propagation.MapCarrier(meta))
+}
+
+// StartEventSpan starts a span for handling the stored event. The span continues
+// the trace captured in the event meta on append (if any) so a single trace spans
+// from the command appending the event to the handling of the event.
+// If ctx carries a span of its own, the started span is linked to it
+// (or is its child if the event meta carries no trace context)
+func StartEventSpan(ctx context.Context, t trace.Tracer, name string, evt StoredEvent, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			AttrStream.String(evt.StreamID),
+			AttrVersion.Int(evt.StreamVersion),
+			AttrSequence.Int64(int64(evt.Sequence)),
+			AttrEventType.String(evt.Type),
+		),
+		trace.WithAttributes(attrs...),
+	}
+
+	remote := trace.SpanContextFromContext(ExtractTraceContext(context.Background(), evt.Meta))
+
+	if remote.IsValid() {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
+
+		ctx = trace.ContextWithRemoteSpanContext(ctx, remote)
+	}
+
+	return t.Start(ctx, name, opts...)
+}
+
+// tracePoll records a span for a subscription poll which read events
+// (polls which did not read any events are not recorded)
+func tracePoll(ctx context.Context, start time.Time, offset, n int) {
+	_, span := tracing.Tracer().Start(
+		ctx,
+		"eventstore.SubscribeAll.poll",
+		trace.WithTimestamp(start),
+		trace.WithAttributes(
+			AttrSequence.Int(offset),
+			AttrEventCount.Int(n),
+		),
+	)
+
+	span.End()
+}
@@ -0,0 +1,144 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+	})
+
+	return sr
+}
+
+func endedSpan(sr *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range sr.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	return nil
+}
+
+func TestAppendStreamCapturesTraceContext(t *testing.T) {
+	sr := spanRecorder(t)
+
+	es, cleanup := eventStoreWithDec(t, eventstore.NewJSONEncoder(SomeEvent{}), eventstore.WithTraceContext())
+
+	defer cleanup()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "command")
+
+	err := es.AppendStream(ctx, "other-stream", eventstore.InitialStreamVersion, toEventToStore(SomeEvent{UserID: "user-2"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent.End()
+
+	evts, err := es.ReadStream(context.Background(), "other-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, evts[0].Meta["traceparent"], parent.SpanContext().TraceID().String())
+	assert.Equal(t, "127.0.0.1", evts[0].Meta["ip"])
+
+	span := endedSpan(sr, "eventstore.AppendStream")
+
+	if span == nil {
+		t.Fatal("append stream span should have been recorded")
+	}
+
+	assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+	assert.Contains(t, span.Attributes(), eventstore.AttrStream.String("other-stream"))
+	assert.Contains(t, span.Attributes(), eventstore.AttrEventCount.Int(1))
+
+	assert.NotNil(t, endedSpan(sr, "eventstore.ReadStream"))
+}
+
+func TestAppendStreamDoesNotCaptureTraceContextByDefault(t *testing.T) {
+	spanRecorder(t)
+
+	es, cleanup := eventStore(t)
+
+	defer cleanup()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "command")
+
+	err := es.AppendStream(ctx, "stream", eventstore.InitialStreamVersion, toEventToStore(SomeEvent{UserID: "user-1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent.End()
+
+	evts, err := es.ReadStream(context.Background(), "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]string{"ip": "127.0.0.1"}, evts[0].Meta)
+}
+
+func TestProjectorContinuesTraceOfAppendedEvents(t *testing.T) {
+	sr := spanRecorder(t)
+
+	es, cleanup := eventStoreWithDec(t, eventstore.NewJSONEncoder(SomeEvent{}), eventstore.WithTraceContext())
+
+	defer cleanup()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "command")
+
+	err := es.AppendStream(ctx, "stream", eventstore.InitialStreamVersion, toEventToStore(SomeEvent{UserID: "user-1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent.End()
+
+	var projected trace.SpanContext
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed("users", func(ctx context.Context, _ eventstore.StoredEvent) error {
+		projected = trace.SpanContextFromContext(ctx)
+
+		return nil
+	})
+
+	rctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+
+	defer cancel()
+
+	assert.NoError(t, p.Run(rctx))
+
+	assert.Equal(t, parent.SpanContext().TraceID(), projected.TraceID())
+
+	span := endedSpan(sr, "eventstore.Project")
+
+	if span == nil {
+		t.Fatal("projection span should have been recorded")
+	}
+
+	assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+	assert.Contains(t, span.Attributes(), eventstore.AttrProjection.String("users"))
+}
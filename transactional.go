@@ -0,0 +1,100 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/aneshas/tx/v2"
+)
+
+// WithTransaction is a projection option which makes the projector handle events in
+// transactions started using the transactor (eg. tx.New(gormtx.NewDB(eventStore.DB))).
+// The checkpoint is stored in the same transaction, which is passed to the projection via
+// context (eg. gormtx.From(ctx)). This way, if the read model lives in the same database
+// as the event store, read model writes and the checkpoint are committed atomically, so
+// each event is projected exactly once.
+// Up to batchSize events already read from the event store are handled per transaction.
+// The projection needs to be named and the checkpoint store needs to take part in the
+// transaction passed via context (EventStore does)
+func WithTransaction(t tx.Transactor, batchSize int) ProjectionOpt {
+	return func(cfg ProjectionCfg) ProjectionCfg {
+		cfg.transactor = t
+		cfg.txBatch = max(batchSize, 1)
+
+		return cfg
+	}
+}
+
+func (p *Projector) runTransactional(ctx context.Context, sub Subscription, r registration, cp *checkpointer) error {
+	for {
+		select {
+		case data := <-sub.EventData:
+			batch := []StoredEvent{data}
+
+		fill:
+			for len(batch) < r.cfg.txBatch {
+				select {
+				case data := <-sub.EventData:
+					batch = append(batch, data)
+
+				default:
+					break fill
+				}
+			}
+
+			last := batch[len(batch)-1]
+
+			err := r.cfg.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+				for _, evt := range batch {
+					if err := r.handle(ctx, evt); err != nil {
+						return err
+					}
+				}
+
+				return cp.store.SaveCheckpoint(ctx, cp.name, last.Sequence)
+			})
+			if err != nil {
+				p.logErr(err)
+
+				return err
+			}
+
+			cp.saved = last.Sequence
+
+			for _, evt := range batch {
+				r.stats.processed(evt.Sequence, evt.OccurredOn)
+			}
+
+		case err := <-sub.Err:
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					if seq, ok := sub.skipped(); ok && seq > cp.saved {
+						cp.save(ctx, seq)
+
+						r.stats.advance(seq)
+					}
+
+					if r.caughtUp != nil && len(sub.EventData) == 0 {
+						r.caughtUp()
+					}
+
+					break
+				}
+
+				if errors.Is(err, ErrSubscriptionClosedByClient) {
+					return nil
+				}
+
+				p.logErr(err)
+
+				if isPoison(err) {
+					return err
+				}
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
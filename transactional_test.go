@@ -0,0 +1,110 @@
+package eventstore_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/tx/v2"
+	"github.com/aneshas/tx/v2/gormtx"
+	"github.com/stretchr/testify/assert"
+)
+
+type userReadModel struct {
+	UserID string `gorm:"primaryKey"`
+}
+
+func TestTransactionalProjectionShouldCommitReadModelAndCheckpointAtomically(t *testing.T) {
+	for _, batchSize := range []int{1, 10} {
+		t.Run(fmt.Sprintf("batch size %d", batchSize), func(t *testing.T) {
+			es, cleanup := eventStore(t)
+
+			defer cleanup()
+
+			err := es.DB.AutoMigrate(&userReadModel{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = es.AppendStream(
+				context.Background(), "stream", eventstore.InitialStreamVersion,
+				toEventToStore(SomeEvent{UserID: "user-1"}, SomeEvent{UserID: "user-2"}, SomeEvent{UserID: "user-3"}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var (
+				failed      bool
+				checkpoints []uint64
+			)
+
+			p := eventstore.NewProjector(es)
+
+			p.AddNamed(
+				"users",
+				func(ctx context.Context, evt eventstore.StoredEvent) error {
+					db, ok := gormtx.From(ctx)
+					if !ok {
+						return fmt.Errorf("transaction should have been passed")
+					}
+
+					err := db.Create(&userReadModel{UserID: evt.Event.(SomeEvent).UserID}).Error
+					if err != nil {
+						return err
+					}
+
+					if evt.Sequence == 2 && !failed {
+						failed = true
+
+						seq, _ := es.Checkpoint(context.Background(), "users")
+
+						checkpoints = append(checkpoints, seq)
+
+						return fmt.Errorf("some transient error")
+					}
+
+					return nil
+				},
+				eventstore.WithTransaction(tx.New(gormtx.NewDB(es.DB)), batchSize),
+			)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+
+			defer cancel()
+
+			assert.NoError(t, p.Run(ctx))
+
+			// read model primary key would be violated on retry
+			// had the failed transaction been partially committed
+			var got []userReadModel
+
+			assert.NoError(t, es.DB.Order("user_id asc").Find(&got).Error)
+			assert.Equal(t, []userReadModel{{"user-1"}, {"user-2"}, {"user-3"}}, got)
+
+			seq, err := es.Checkpoint(context.Background(), "users")
+
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(3), seq)
+			assert.True(t, failed)
+
+			if batchSize == 1 {
+				assert.Equal(t, []uint64{1}, checkpoints)
+			}
+		})
+	}
+}
+
+func TestTransactionalProjectionRequiresCheckpointStore(t *testing.T) {
+	p := eventstore.NewProjector(streamer{})
+
+	p.AddNamed(
+		"users",
+		func(_ context.Context, _ eventstore.StoredEvent) error { return nil },
+		eventstore.WithTransaction(tx.New(nil), 1),
+	)
+
+	assert.Error(t, p.Run(context.Background()))
+}
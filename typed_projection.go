@@ -0,0 +1,85 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/aneshas/eventstore/internal/events"
+)
+
+// NewTypedProjection constructs a TypedProjection
+// Register event handlers using Handle
+func NewTypedProjection() *TypedProjection {
+	return &TypedProjection{
+		handlers: make(map[string]ProjectionFunc),
+	}
+}
+
+// TypedProjection is a projection which dispatches events to handlers registered
+// per event type, so there is no need to type switch on StoredEvent.Event.
+// Events of types without a registered handler are skipped
+type TypedProjection struct {
+	handlers map[string]ProjectionFunc
+	types    []string
+}
+
+// Handle registers a handler for events of type E with the typed projection.
+// Events are matched by their type name (the same way JsonEncoder names them)
+// and the stored event is passed to the handler along with the event
+// in order to provide access to meta data.
+// E can be either the event type or a pointer to it (eg. Handle[*AccountOpened]).
+// Handle panics if E is not a named type, since such events cannot be matched
+func Handle[E any](p *TypedProjection, h func(ctx context.Context, evt E, data StoredEvent) error) {
+	t := reflect.TypeFor[E]()
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	name := t.Name()
+	if name == "" {
+		panic(fmt.Sprintf("eventstore: cannot handle events of unnamed type %s", reflect.TypeFor[E]()))
+	}
+
+	if _, ok := p.handlers[name]; !ok {
+		p.types = append(p.types, name)
+	}
+
+	p.handlers[name] = func(ctx context.Context, data StoredEvent) error {
+		decoded, err := data.Decode()
+		if err != nil {
+			return err
+		}
+
+		evt, ok := events.As[E](decoded)
+		if !ok {
+			return fmt.Errorf("event %s is of unexpected type %T", data.Type, decoded)
+		}
+
+		return h(ctx, evt, data)
+	}
+}
+
+// Project dispatches the event to its handler (if any)
+func (p *TypedProjection) Project(ctx context.Context, data StoredEvent) error {
+	h, ok := p.handlers[data.Type]
+	if !ok {
+		return nil
+	}
+
+	return h(ctx, data)
+}
+
+// Types returns type names of the handled events
+func (p *TypedProjection) Types() []string {
+	return p.types
+}
+
+// Filter returns a projection option which makes the projector subscribe only
+// to the handled event types, thus filtering them at the source eg:
+//
+// projector.AddNamed("accounts", p.Project, p.Filter())
+func (p *TypedProjection) Filter() ProjectionOpt {
+	return WithSubscriptionOpts(WithEventTypes(p.Types()...))
+}
@@ -0,0 +1,200 @@
+package eventstore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedProjectionShouldDispatchEventsByType(t *testing.T) {
+	var (
+		users  []string
+		smths  []string
+		stream string
+	)
+
+	p := eventstore.NewTypedProjection()
+
+	eventstore.Handle(p, func(_ context.Context, evt SomeEvent, data eventstore.StoredEvent) error {
+		users = append(users, evt.UserID)
+		stream = data.StreamID
+
+		return nil
+	})
+
+	eventstore.Handle(p, func(_ context.Context, evt AnotherEvent, _ eventstore.StoredEvent) error {
+		smths = append(smths, evt.Smth)
+
+		return nil
+	})
+
+	ctx := context.Background()
+
+	assert.NoError(t, p.Project(ctx, eventstore.StoredEvent{Event: SomeEvent{UserID: "user-1"}, Type: "SomeEvent", StreamID: "stream"}))
+	assert.NoError(t, p.Project(ctx, eventstore.StoredEvent{Event: AnotherEvent{Smth: "foo"}, Type: "AnotherEvent"}))
+	assert.NoError(t, p.Project(ctx, eventstore.StoredEvent{Event: struct{}{}, Type: "UnhandledEvent"}))
+
+	assert.Equal(t, []string{"user-1"}, users)
+	assert.Equal(t, []string{"foo"}, smths)
+	assert.Equal(t, "stream", stream)
+	assert.Equal(t, []string{"SomeEvent", "AnotherEvent"}, p.Types())
+}
+
+func TestTypedProjectionShouldReportMismatchedEvent(t *testing.T) {
+	p := eventstore.NewTypedProjection()
+
+	eventstore.Handle(p, func(_ context.Context, _ SomeEvent, _ eventstore.StoredEvent) error {
+		return nil
+	})
+
+	err := p.Project(context.Background(), eventstore.StoredEvent{Event: AnotherEvent{}, Type: "SomeEvent"})
+
+	assert.Error(t, err)
+}
+
+func TestTypedProjectionShouldDispatchEventsToPointerHandlers(t *testing.T) {
+	var users []string
+
+	p := eventstore.NewTypedProjection()
+
+	eventstore.Handle(p, func(_ context.Context, evt *SomeEvent, _ eventstore.StoredEvent) error {
+		users = append(users, evt.UserID)
+
+		return nil
+	})
+
+	ctx := context.Background()
+
+	assert.NoError(t, p.Project(ctx, eventstore.StoredEvent{Event: SomeEvent{UserID: "user-1"}, Type: "SomeEvent"}))
+	assert.NoError(t, p.Project(ctx, eventstore.StoredEvent{Event: &SomeEvent{UserID: "user-2"}, Type: "SomeEvent"}))
+
+	assert.Equal(t, []string{"user-1", "user-2"}, users)
+	assert.Equal(t, []string{"SomeEvent"}, p.Types())
+}
+
+func TestTypedProjectionShouldRejectUnnamedEventTypes(t *testing.T) {
+	p := eventstore.NewTypedProjection()
+
+	assert.Panics(t, func() {
+		eventstore.Handle(p, func(_ context.Context, _ map[string]any, _ eventstore.StoredEvent) error {
+			return nil
+		})
+	})
+}
+
+func TestTypedProjectionShouldFilterEventsAtSource(t *testing.T) {
+	es, cleanup := eventStoreWithDec(t, eventstore.NewJSONEncoder(SomeEvent{}, AnotherEvent{}))
+
+	defer cleanup()
+
+	err := es.AppendStream(
+		context.Background(), "stream", eventstore.InitialStreamVersion,
+		toEventToStore(SomeEvent{UserID: "user-1"}, AnotherEvent{Smth: "foo"}, SomeEvent{UserID: "user-2"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		m   sync.Mutex
+		got []eventstore.StoredEvent
+	)
+
+	tp := eventstore.NewTypedProjection()
+
+	eventstore.Handle(tp, func(_ context.Context, _ AnotherEvent, data eventstore.StoredEvent) error {
+		m.Lock()
+		defer m.Unlock()
+
+		got = append(got, data)
+
+		return nil
+	})
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed("smths", tp.Project, tp.Filter())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+
+	defer cancel()
+
+	_ = p.Run(ctx)
+
+	m.Lock()
+	defer m.Unlock()
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, uint64(2), got[0].Sequence)
+}
+
+func TestFilteredProjectionShouldAdvancePastEventsOfOtherTypes(t *testing.T) {
+	es, cleanup := eventStoreWithDec(t, eventstore.NewJSONEncoder(SomeEvent{}, AnotherEvent{}))
+
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	evts := []any{AnotherEvent{Smth: "foo"}}
+
+	for range 10 {
+		evts = append(evts, SomeEvent{UserID: "user-1"})
+	}
+
+	err := es.AppendStream(ctx, "stream", eventstore.InitialStreamVersion, toEventToStore(evts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tp := eventstore.NewTypedProjection()
+
+	eventstore.Handle(tp, func(_ context.Context, _ AnotherEvent, _ eventstore.StoredEvent) error {
+		return nil
+	})
+
+	p := eventstore.NewProjector(es)
+
+	p.AddNamed("smths", tp.Project, tp.Filter())
+
+	go func() { _ = p.Run(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		seq, err := es.Checkpoint(ctx, "smths")
+
+		return err == nil && seq == 11
+	}, 2*time.Second, 50*time.Millisecond)
+
+	statuses, err := p.Status(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(11), statuses[0].Sequence)
+	assert.Equal(t, uint64(0), statuses[0].Lag)
+}
+
+func TestReadAllShouldFilterEventTypes(t *testing.T) {
+	es, cleanup := eventStoreWithDec(t, eventstore.NewJSONEncoder(SomeEvent{}, AnotherEvent{}))
+
+	defer cleanup()
+
+	ctx := context.Background()
+
+	err := es.AppendStream(
+		ctx, "stream", eventstore.InitialStreamVersion,
+		toEventToStore(SomeEvent{UserID: "user-1"}, AnotherEvent{Smth: "foo"}, SomeEvent{UserID: "user-2"}, AnotherEvent{Smth: "bar"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := es.ReadAll(ctx, eventstore.WithEventTypes("SomeEvent"), eventstore.WithBatchSize(1))
+
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, SomeEvent{UserID: "user-2"}, got[1].Event)
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidSignature is returned when a payload signature does not match
+var ErrInvalidSignature = errors.New("webhook: invalid signature")
+
+const signaturePrefix = "sha256="
+
+// Sign returns the signature of the payload (sent as HeaderSignature)
+// which is the hex encoded HMAC-SHA256 of the payload prefixed with sha256=
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that the signature is the signature of the payload
+func Verify(secret string, payload []byte, signature string) error {
+	sig, ok := strings.CutPrefix(signature, signaturePrefix)
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// ReadVerified reads the body of a webhook request and verifies its signature.
+// The body can then be passed to ambar.Ambar.Project
+func ReadVerified(r *http.Request, secret string) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Verify(secret, body, r.Header.Get(HeaderSignature))
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
@@ -0,0 +1,60 @@
+package webhook_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aneshas/eventstore/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldVerifySignedPayload(t *testing.T) {
+	payload := []byte(`{"payload":{}}`)
+
+	sig := webhook.Sign("secret", payload)
+
+	assert.Equal(t, "sha256=", sig[:7])
+	assert.NoError(t, webhook.Verify("secret", payload, sig))
+}
+
+func TestShouldRejectInvalidSignatures(t *testing.T) {
+	payload := []byte(`{"payload":{}}`)
+
+	sig := webhook.Sign("secret", payload)
+
+	for name, tc := range map[string]struct {
+		secret  string
+		payload []byte
+		sig     string
+	}{
+		"wrong secret":     {"other", payload, sig},
+		"tampered payload": {"secret", []byte(`{"payload":[]}`), sig},
+		"missing prefix":   {"secret", payload, sig[7:]},
+		"not hex":          {"secret", payload, "sha256=zz"},
+		"empty":            {"secret", payload, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, webhook.Verify(tc.secret, tc.payload, tc.sig), webhook.ErrInvalidSignature)
+		})
+	}
+}
+
+func TestShouldReadVerifiedRequestBody(t *testing.T) {
+	payload := []byte(`{"payload":{}}`)
+
+	r := httptest.NewRequest("POST", "/", bytes.NewReader(payload))
+	r.Header.Set(webhook.HeaderSignature, webhook.Sign("secret", payload))
+
+	body, err := webhook.ReadVerified(r, "secret")
+
+	assert.NoError(t, err)
+	assert.Equal(t, payload, body)
+
+	r = httptest.NewRequest("POST", "/", bytes.NewReader(payload))
+	r.Header.Set(webhook.HeaderSignature, webhook.Sign("other", payload))
+
+	_, err = webhook.ReadVerified(r, "secret")
+
+	assert.ErrorIs(t, err, webhook.ErrInvalidSignature)
+}
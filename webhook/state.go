@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormSubscriber struct {
+	ID        string `gorm:"primaryKey"`
+	Sequence  uint64
+	Failures  int
+	Disabled  bool
+	LastError string
+	UpdatedAt time.Time
+}
+
+// TableName returns gorm table name
+func (gs *gormSubscriber) TableName() string { return "webhook_subscriber" }
+
+// State represents the delivery state of a subscriber
+type State struct {
+	ID string
+
+	// Sequence is the sequence of the last event delivered to the subscriber
+	Sequence uint64
+
+	// Failures is the number of consecutive failed delivery attempts
+	Failures  int
+	Disabled  bool
+	LastError string
+	UpdatedAt time.Time
+}
+
+// State returns the delivery state of the subscriber
+// (zero state if nothing has been delivered to the subscriber yet)
+func (d *Dispatcher) State(ctx context.Context, id string) (State, error) {
+	var gs []gormSubscriber
+
+	err := d.db.WithContext(ctx).
+		Where("id = ?", id).
+		Limit(1).
+		Find(&gs).Error
+	if err != nil {
+		return State{}, err
+	}
+
+	if len(gs) == 0 {
+		return State{ID: id}, nil
+	}
+
+	return State(gs[0]), nil
+}
+
+// Enable enables a subscriber disabled after repeated delivery failures
+// (see WithMaxFailures). Delivery continues from the event that failed
+func (d *Dispatcher) Enable(ctx context.Context, id string) error {
+	return d.db.WithContext(ctx).
+		Model(&gormSubscriber{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"disabled":   false,
+			"failures":   0,
+			"updated_at": time.Now().UTC(),
+		}).Error
+}
+
+// Disable stops delivery to the subscriber (see Enable). Delivery stops before
+// the next event or after the next failed attempt to deliver the current one
+func (d *Dispatcher) Disable(ctx context.Context, id string) error {
+	return d.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"disabled", "updated_at"}),
+		}).
+		Create(&gormSubscriber{ID: id, Disabled: true, UpdatedAt: time.Now().UTC()}).Error
+}
+
+// delivered records the delivery of the event with the given sequence, leaving
+// the disabled flag (which might have been changed by an operator) intact
+func (d *Dispatcher) delivered(ctx context.Context, id string, seq uint64) error {
+	gs := gormSubscriber{
+		ID:        id,
+		Sequence:  seq,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	return d.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"sequence", "failures", "last_error", "updated_at"}),
+		}).
+		Create(&gs).Error
+}
+
+// failed records a failed delivery attempt, disabling the subscriber after max
+// consecutive failures (if max is positive). Failures are counted in the database,
+// so a subscriber enabled in the meantime (see Enable) starts counting from zero
+func (d *Dispatcher) failed(ctx context.Context, id string, max int, cause error) (State, error) {
+	err := d.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&gormSubscriber{ID: id, UpdatedAt: time.Now().UTC()}).Error
+	if err != nil {
+		return State{}, err
+	}
+
+	updates := map[string]any{
+		"failures":   gorm.Expr("failures + 1"),
+		"last_error": cause.Error(),
+		"updated_at": time.Now().UTC(),
+	}
+
+	if max > 0 {
+		updates["disabled"] = gorm.Expr("disabled OR failures + 1 >= ?", max)
+	}
+
+	err = d.db.WithContext(ctx).
+		Model(&gormSubscriber{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+	if err != nil {
+		return State{}, err
+	}
+
+	return d.State(ctx, id)
+}
@@ -0,0 +1,482 @@
+// Package webhook delivers events to subscribers' HTTP endpoints.
+//
+// Events are POSTed using the ambar.Payload JSON layout, so the same
+// ambar.Ambar.Project handler (eg. echoambar) can consume them, and are signed
+// using the subscriber secret (see Sign, Verify and ReadVerified).
+// Each subscriber receives events in order of their global sequence, independently
+// of other subscribers. Failed deliveries are retried with exponential backoff and
+// a subscriber is disabled after repeated failures (see WithMaxFailures, Enable and Disable).
+// Delivery state is stored in the event store database (webhook_subscriber table).
+// Unless a locker is configured (see WithLocker), only a single dispatcher
+// instance may run, otherwise each instance delivers every event.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/ambar"
+	"gorm.io/gorm"
+)
+
+// Request headers
+const (
+	HeaderSignature   = "X-Eventstore-Signature"
+	HeaderEventID     = "X-Eventstore-Event-Id"
+	HeaderEventType   = "X-Eventstore-Event-Type"
+	HeaderSubscriber  = "X-Eventstore-Subscriber"
+	contentTypeHeader = "Content-Type"
+)
+
+// disabledPollInterval is the interval at which the state of a disabled
+// subscriber is checked in order to resume delivery once it is enabled
+const disabledPollInterval = 5 * time.Second
+
+// maxResponseSize is the max size of the response body read in order to
+// check for ambar error responses
+const maxResponseSize = 64 << 10
+
+var (
+	// ErrDeliveryFailed is returned (recorded as the last error) when the
+	// subscriber responds with a non 2xx status or an ambar retry response
+	ErrDeliveryFailed = errors.New("webhook: delivery failed")
+
+	// ErrDuplicateSubscriber is returned by Run if subscriber IDs are not unique
+	ErrDuplicateSubscriber = errors.New("webhook: duplicate subscriber")
+)
+
+// Subscriber represents a webhook subscriber
+type Subscriber struct {
+	// ID identifies the subscriber delivery state, so it needs to be stable
+	ID string
+
+	// URL is the endpoint events are POSTed to
+	URL string
+
+	// Secret is used to sign the payloads
+	Secret string
+
+	// EventTypes optionally limits the events delivered to the given types
+	EventTypes []string
+
+	// Filter optionally limits the events delivered to the ones it returns true for.
+	// Events passed to Filter are not decoded (see eventstore.WithLazyDecoding)
+	Filter func(eventstore.StoredEvent) bool
+}
+
+// Cfg (configure using Opt)
+type Cfg struct {
+	client      *http.Client
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	maxFailures int
+	logger      *log.Logger
+	locker      eventstore.Locker
+}
+
+// Opt represents dispatcher configuration option
+type Opt func(Cfg) Cfg
+
+// WithHTTPClient sets the http client used to deliver events
+// (a client with a 10s timeout is used by default)
+func WithHTTPClient(c *http.Client) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.client = c
+
+		return cfg
+	}
+}
+
+// WithBackoff sets the exponential backoff between delivery attempts
+// (1s doubling up to 5m by default)
+func WithBackoff(min, max time.Duration) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.minBackoff = min
+		cfg.maxBackoff = max
+
+		return cfg
+	}
+}
+
+// WithMaxFailures sets the number of consecutive failed delivery attempts
+// after which a subscriber is disabled (10 by default)
+func WithMaxFailures(n int) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.maxFailures = n
+
+		return cfg
+	}
+}
+
+// WithLogger sets the logger delivery errors are logged with (log.Default() by default)
+func WithLogger(l *log.Logger) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.logger = l
+
+		return cfg
+	}
+}
+
+// WithLocker makes the dispatcher acquire a lease for each subscriber before delivering
+// events to it (see eventstore.EventStore.Locker). This way each subscriber is served by
+// a single dispatcher instance even if multiple instances are running. If the instance
+// holding the lease dies, the lease is acquired by one of the remaining instances
+func WithLocker(l eventstore.Locker) Opt {
+	return func(cfg Cfg) Cfg {
+		cfg.locker = l
+
+		return cfg
+	}
+}
+
+// New constructs a new webhook dispatcher delivering events from the event store
+func New(es *eventstore.EventStore, opts ...Opt) (*Dispatcher, error) {
+	cfg := Cfg{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		minBackoff:  time.Second,
+		maxBackoff:  5 * time.Minute,
+		maxFailures: 10,
+		logger:      log.Default(),
+	}
+
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+
+	err := es.DB.AutoMigrate(&gormSubscriber{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Dispatcher{
+		es:  es,
+		db:  es.DB,
+		cfg: cfg,
+	}, nil
+}
+
+// Dispatcher delivers events to webhook subscribers
+type Dispatcher struct {
+	es          *eventstore.EventStore
+	db          *gorm.DB
+	cfg         Cfg
+	subscribers []Subscriber
+}
+
+// Add registers subscribers with the dispatcher
+// Make sure to add all of your subscribers before calling Run
+func (d *Dispatcher) Add(subscribers ...Subscriber) {
+	d.subscribers = append(d.subscribers, subscribers...)
+}
+
+// Run delivers events to all subscribers until ctx is canceled
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ids := make(map[string]struct{})
+
+	for _, s := range d.subscribers {
+		if _, ok := ids[s.ID]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateSubscriber, s.ID)
+		}
+
+		ids[s.ID] = struct{}{}
+	}
+
+	var wg sync.WaitGroup
+
+	for _, s := range d.subscribers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			d.run(ctx, s)
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+func (d *Dispatcher) run(ctx context.Context, s Subscriber) {
+	if d.cfg.locker == nil {
+		d.dispatch(ctx, s)
+
+		return
+	}
+
+	for ctx.Err() == nil {
+		lease, err := d.cfg.locker.Acquire(ctx, leaseName(s))
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logErr(s, err)
+				sleep(ctx, d.cfg.minBackoff)
+			}
+
+			continue
+		}
+
+		lctx, cancel := context.WithCancel(ctx)
+
+		go func() {
+			select {
+			case <-lease.Lost():
+				cancel()
+
+			case <-lctx.Done():
+			}
+		}()
+
+		d.dispatch(lctx, s)
+
+		cancel()
+
+		if err := lease.Release(context.WithoutCancel(ctx)); err != nil {
+			d.logErr(s, err)
+		}
+	}
+}
+
+func leaseName(s Subscriber) string {
+	return "webhook:" + s.ID
+}
+
+// dispatch delivers events to the subscriber until ctx is canceled
+func (d *Dispatcher) dispatch(ctx context.Context, s Subscriber) {
+	for ctx.Err() == nil {
+		state, err := d.State(ctx, s.ID)
+		if err != nil {
+			d.logErr(s, err)
+
+			if !sleep(ctx, d.cfg.minBackoff) {
+				return
+			}
+
+			continue
+		}
+
+		if state.Disabled {
+			if !sleep(ctx, disabledPollInterval) {
+				return
+			}
+
+			continue
+		}
+
+		d.deliverAll(ctx, s, state)
+	}
+}
+
+// deliverAll delivers events to the subscriber until ctx is canceled,
+// the subscriber is disabled or reading events fails
+func (d *Dispatcher) deliverAll(ctx context.Context, s Subscriber, state State) {
+	opts := []eventstore.SubAllOpt{
+		eventstore.WithOffset(int(state.Sequence)),
+		eventstore.WithLazyDecoding(),
+	}
+
+	if len(s.EventTypes) > 0 {
+		opts = append(opts, eventstore.WithEventTypes(s.EventTypes...))
+	}
+
+	sub, err := d.es.SubscribeAll(ctx, opts...)
+	if err != nil {
+		d.logErr(s, err)
+		sleep(ctx, d.cfg.minBackoff)
+
+		return
+	}
+
+	defer sub.Close()
+
+	for {
+		select {
+		case evt := <-sub.EventData:
+			if s.Filter != nil && !s.Filter(evt) {
+				continue
+			}
+
+			// The subscriber might have been disabled meanwhile
+			if state, err := d.State(ctx, s.ID); err != nil || state.Disabled {
+				return
+			}
+
+			if !d.deliverWithRetry(ctx, s, evt) {
+				return
+			}
+
+		case err := <-sub.Err:
+			if errors.Is(err, io.EOF) {
+				continue
+			}
+
+			if ctx.Err() == nil {
+				d.logErr(s, err)
+				sleep(ctx, d.cfg.minBackoff)
+			}
+
+			return
+		}
+	}
+}
+
+// deliverWithRetry delivers the event retrying failed attempts and reports
+// whether delivery should continue with the next event
+func (d *Dispatcher) deliverWithRetry(ctx context.Context, s Subscriber, evt eventstore.StoredEvent) bool {
+	backoff := d.cfg.minBackoff
+
+	for {
+		err := d.deliver(ctx, s, evt)
+		if err != nil && ctx.Err() != nil {
+			return false
+		}
+
+		if err == nil {
+			if serr := d.delivered(context.WithoutCancel(ctx), s.ID, evt.Sequence); serr != nil {
+				d.logErr(s, serr)
+
+				return false
+			}
+
+			return true
+		}
+
+		state, serr := d.failed(context.WithoutCancel(ctx), s.ID, d.cfg.maxFailures, err)
+		if serr != nil {
+			d.logErr(s, serr)
+
+			return false
+		}
+
+		if state.Disabled {
+			d.logErr(s, fmt.Errorf("disabled after %d failed deliveries: %w", state.Failures, err))
+
+			return false
+		}
+
+		if !sleep(ctx, backoff) {
+			return false
+		}
+
+		backoff = min(backoff*2, d.cfg.maxBackoff)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, s Subscriber, evt eventstore.StoredEvent) error {
+	body, err := payload(evt)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(contentTypeHeader, "application/json")
+	req.Header.Set(HeaderSignature, Sign(s.Secret, body))
+	req.Header.Set(HeaderEventID, evt.ID)
+	req.Header.Set(HeaderEventType, evt.Type)
+	req.Header.Set(HeaderSubscriber, s.ID)
+
+	resp, err := d.cfg.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: %s", ErrDeliveryFailed, resp.Status)
+	}
+
+	return ambarResult(resp.Body)
+}
+
+// ambarResult checks whether the response is an ambar retry response
+// (see ambar.RetryResp). Other responses (including ambar keep going) are successful
+func ambarResult(body io.Reader) error {
+	var resp struct {
+		Result struct {
+			Error *struct {
+				Policy      string `json:"policy"`
+				Description string `json:"description"`
+			} `json:"error"`
+		} `json:"result"`
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if json.Unmarshal(data, &resp) != nil || resp.Result.Error == nil {
+		return nil
+	}
+
+	if resp.Result.Error.Policy == "must_retry" {
+		return fmt.Errorf("%w: %s", ErrDeliveryFailed, resp.Result.Error.Description)
+	}
+
+	return nil
+}
+
+// payload returns the event encoded as an ambar projection request (see ambar.Req)
+func payload(evt eventstore.StoredEvent) ([]byte, error) {
+	var meta *string
+
+	if len(evt.Meta) > 0 {
+		m, err := json.Marshal(evt.Meta)
+		if err != nil {
+			return nil, err
+		}
+
+		ms := string(m)
+
+		meta = &ms
+	}
+
+	return json.Marshal(ambar.Req{
+		Payload: ambar.Payload{
+			Event:              evt.Raw.Data,
+			Meta:               meta,
+			ID:                 evt.ID,
+			Sequence:           evt.Sequence,
+			Type:               evt.Type,
+			CausationEventID:   evt.CausationEventID,
+			CorrelationEventID: evt.CorrelationEventID,
+			StreamID:           evt.StreamID,
+			StreamVersion:      evt.StreamVersion,
+			OccurredOn:         evt.OccurredOn.Format(time.RFC3339Nano),
+		},
+	})
+}
+
+func (d *Dispatcher) logErr(s Subscriber, err error) {
+	d.cfg.logger.Printf("webhook: subscriber %s: %v", s.ID, err)
+}
+
+// sleep waits for d and reports whether ctx is still active
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+
+	case <-t.C:
+		return true
+	}
+}
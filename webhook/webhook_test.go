@@ -0,0 +1,572 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aneshas/eventstore"
+	"github.com/aneshas/eventstore/ambar"
+	"github.com/aneshas/eventstore/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+type itemAdded struct {
+	N int
+}
+
+type itemRemoved struct {
+	N int
+}
+
+var enc = eventstore.NewJSONEncoder(itemAdded{}, itemRemoved{})
+
+func newEventStore(t *testing.T) *eventstore.EventStore {
+	t.Helper()
+
+	es, err := eventstore.New(enc, eventstore.WithSQLiteDB(filepath.Join(t.TempDir(), "webhook.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = es.Close()
+	})
+
+	return es
+}
+
+func newDispatcher(t *testing.T, es *eventstore.EventStore, opts ...webhook.Opt) *webhook.Dispatcher {
+	t.Helper()
+
+	d, err := webhook.New(es, append([]webhook.Opt{
+		webhook.WithBackoff(time.Millisecond, 5*time.Millisecond),
+		webhook.WithLogger(log.New(io.Discard, "", 0)),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+// endpoint is a subscriber endpoint projecting events using ambar
+type endpoint struct {
+	mu       sync.Mutex
+	secret   string
+	respond  func(calls int) (int, string)
+	calls    int
+	events   []eventstore.StoredEvent
+	requests []*http.Request
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.calls++
+	e.requests = append(e.requests, r)
+
+	if e.respond != nil {
+		status, body := e.respond(e.calls)
+		if status != http.StatusOK || body != ambar.SuccessResp {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+
+			return
+		}
+	}
+
+	body, err := webhook.ReadVerified(r, e.secret)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	err = ambar.New(enc).Project(r, func(_ *http.Request, evt eventstore.StoredEvent) error {
+		e.events = append(e.events, evt)
+
+		return nil
+	}, body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	_, _ = w.Write([]byte(ambar.SuccessResp))
+}
+
+func (e *endpoint) projected() []eventstore.StoredEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]eventstore.StoredEvent{}, e.events...)
+}
+
+func (e *endpoint) callCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.calls
+}
+
+func serve(t *testing.T, e *endpoint) string {
+	t.Helper()
+
+	srv := httptest.NewServer(e)
+
+	t.Cleanup(srv.Close)
+
+	return srv.URL
+}
+
+func delivered(d *webhook.Dispatcher, id string, seq uint64) func() bool {
+	return func() bool {
+		state, err := d.State(context.Background(), id)
+
+		return err == nil && state.Sequence == seq
+	}
+}
+
+func run(t *testing.T, d *webhook.Dispatcher, until func() bool) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+
+	go func() {
+		done <- d.Run(ctx)
+	}()
+
+	assert.Eventually(t, until, 5*time.Second, 5*time.Millisecond)
+
+	cancel()
+
+	assert.NoError(t, <-done)
+}
+
+func TestShouldDeliverSignedAmbarPayloads(t *testing.T) {
+	es := newEventStore(t)
+
+	ctx := context.Background()
+
+	err := es.AppendStream(ctx, "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}, Meta: map[string]string{"foo": "bar"}, CorrelationEventID: "correlation-id"},
+		{Event: itemAdded{N: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := endpoint{secret: "secret"}
+
+	d := newDispatcher(t, es)
+
+	d.Add(webhook.Subscriber{ID: "partner", URL: serve(t, &e), Secret: "secret"})
+
+	run(t, d, delivered(d, "partner", 2))
+
+	evts := e.projected()
+
+	assert.Equal(t, itemAdded{N: 1}, evts[0].Event)
+	assert.Equal(t, itemAdded{N: 2}, evts[1].Event)
+	assert.Equal(t, uint64(1), evts[0].Sequence)
+	assert.Equal(t, "stream-1", evts[0].StreamID)
+	assert.Equal(t, "bar", evts[0].Meta["foo"])
+	assert.Equal(t, "correlation-id", *evts[0].CorrelationEventID)
+	assert.False(t, evts[0].OccurredOn.IsZero())
+
+	r := e.requests[0]
+
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	assert.Equal(t, evts[0].ID, r.Header.Get(webhook.HeaderEventID))
+	assert.Equal(t, "itemAdded", r.Header.Get(webhook.HeaderEventType))
+	assert.Equal(t, "partner", r.Header.Get(webhook.HeaderSubscriber))
+
+	state, err := d.State(ctx, "partner")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), state.Sequence)
+	assert.Equal(t, 0, state.Failures)
+}
+
+func TestShouldFilterEventsPerSubscriber(t *testing.T) {
+	es := newEventStore(t)
+
+	ctx := context.Background()
+
+	err := es.AppendStream(ctx, "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+		{Event: itemRemoved{N: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = es.AppendStream(ctx, "stream-2", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added := endpoint{secret: "added"}
+	stream := endpoint{secret: "stream"}
+
+	d := newDispatcher(t, es)
+
+	d.Add(
+		webhook.Subscriber{ID: "added", URL: serve(t, &added), Secret: "added", EventTypes: []string{"itemAdded"}},
+		webhook.Subscriber{ID: "stream", URL: serve(t, &stream), Secret: "stream", Filter: func(evt eventstore.StoredEvent) bool {
+			return evt.StreamID == "stream-1"
+		}},
+	)
+
+	run(t, d, func() bool { return len(added.projected()) == 2 && len(stream.projected()) == 2 })
+
+	assert.Equal(t, itemAdded{N: 1}, added.projected()[0].Event)
+	assert.Equal(t, itemAdded{N: 2}, added.projected()[1].Event)
+
+	assert.Equal(t, itemAdded{N: 1}, stream.projected()[0].Event)
+	assert.Equal(t, itemRemoved{N: 1}, stream.projected()[1].Event)
+}
+
+func TestShouldRetryFailedDeliveries(t *testing.T) {
+	es := newEventStore(t)
+
+	err := es.AppendStream(context.Background(), "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := endpoint{
+		secret: "secret",
+		respond: func(calls int) (int, string) {
+			switch calls {
+			case 1:
+				return http.StatusInternalServerError, ""
+
+			case 2:
+				return http.StatusOK, ambar.RetryResp
+
+			default:
+				return http.StatusOK, ambar.SuccessResp
+			}
+		},
+	}
+
+	d := newDispatcher(t, es)
+
+	d.Add(webhook.Subscriber{ID: "partner", URL: serve(t, &e), Secret: "secret"})
+
+	run(t, d, delivered(d, "partner", 1))
+
+	assert.Equal(t, 3, e.callCount())
+
+	state, err := d.State(context.Background(), "partner")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), state.Sequence)
+	assert.Equal(t, 0, state.Failures)
+	assert.Empty(t, state.LastError)
+}
+
+func TestShouldTreatAmbarKeepGoingAsDelivered(t *testing.T) {
+	es := newEventStore(t)
+
+	err := es.AppendStream(context.Background(), "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+		{Event: itemAdded{N: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := endpoint{
+		secret: "secret",
+		respond: func(calls int) (int, string) {
+			if calls == 1 {
+				return http.StatusOK, ambar.KeepGoingResp
+			}
+
+			return http.StatusOK, ambar.SuccessResp
+		},
+	}
+
+	d := newDispatcher(t, es)
+
+	d.Add(webhook.Subscriber{ID: "partner", URL: serve(t, &e), Secret: "secret"})
+
+	run(t, d, func() bool { return len(e.projected()) == 1 })
+
+	assert.Equal(t, 2, e.callCount())
+	assert.Equal(t, itemAdded{N: 2}, e.projected()[0].Event)
+}
+
+func TestShouldDisableSubscriberAfterRepeatedFailures(t *testing.T) {
+	es := newEventStore(t)
+
+	ctx := context.Background()
+
+	err := es.AppendStream(ctx, "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+		{Event: itemAdded{N: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing := true
+
+	e := endpoint{
+		secret: "secret",
+		respond: func(calls int) (int, string) {
+			if failing {
+				return http.StatusServiceUnavailable, ""
+			}
+
+			return http.StatusOK, ambar.SuccessResp
+		},
+	}
+
+	d := newDispatcher(t, es, webhook.WithMaxFailures(3))
+
+	d.Add(webhook.Subscriber{ID: "partner", URL: serve(t, &e), Secret: "secret"})
+
+	run(t, d, func() bool {
+		state, err := d.State(ctx, "partner")
+
+		return err == nil && state.Disabled
+	})
+
+	state, err := d.State(ctx, "partner")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, state.Failures)
+	assert.Equal(t, uint64(0), state.Sequence)
+	assert.Contains(t, state.LastError, "503 Service Unavailable")
+	assert.Equal(t, 3, e.callCount())
+
+	e.mu.Lock()
+	failing = false
+	e.mu.Unlock()
+
+	assert.NoError(t, d.Enable(ctx, "partner"))
+
+	run(t, d, delivered(d, "partner", 2))
+
+	assert.Len(t, e.projected(), 2)
+
+	state, err = d.State(ctx, "partner")
+
+	assert.NoError(t, err)
+	assert.False(t, state.Disabled)
+	assert.Equal(t, uint64(2), state.Sequence)
+}
+
+func TestShouldNotOverwriteEnableWhileBackingOff(t *testing.T) {
+	es := newEventStore(t)
+
+	err := es.AppendStream(context.Background(), "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := endpoint{
+		secret: "secret",
+		respond: func(_ int) (int, string) {
+			return http.StatusServiceUnavailable, ""
+		},
+	}
+
+	d := newDispatcher(t, es, webhook.WithMaxFailures(3), webhook.WithBackoff(300*time.Millisecond, 300*time.Millisecond))
+
+	d.Add(webhook.Subscriber{ID: "partner", URL: serve(t, &e), Secret: "secret"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	go func() { _ = d.Run(ctx) }()
+
+	failures := func(n int) func() bool {
+		return func() bool {
+			state, err := d.State(ctx, "partner")
+
+			return err == nil && state.Failures == n && !state.Disabled
+		}
+	}
+
+	assert.Eventually(t, failures(2), 5*time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, d.Enable(ctx, "partner"))
+
+	assert.Eventually(t, failures(1), 5*time.Second, 5*time.Millisecond)
+}
+
+func TestShouldDeliverEachEventOnceWithMultipleDispatchers(t *testing.T) {
+	es := newEventStore(t)
+
+	err := es.AppendStream(context.Background(), "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+		{Event: itemAdded{N: 2}},
+		{Event: itemAdded{N: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locker, err := es.Locker(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := endpoint{secret: "secret"}
+	url := serve(t, &e)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+
+	for range 2 {
+		d := newDispatcher(t, es, webhook.WithLocker(locker))
+
+		d.Add(webhook.Subscriber{ID: "partner", URL: url, Secret: "secret"})
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_ = d.Run(ctx)
+		}()
+	}
+
+	d := newDispatcher(t, es)
+
+	assert.Eventually(t, delivered(d, "partner", 3), 5*time.Second, 5*time.Millisecond)
+
+	cancel()
+	wg.Wait()
+
+	assert.Equal(t, 3, e.callCount())
+	assert.Len(t, e.projected(), 3)
+}
+
+func TestShouldStopDeliveringToDisabledSubscriber(t *testing.T) {
+	es := newEventStore(t)
+
+	ctx := context.Background()
+
+	err := es.AppendStream(ctx, "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := endpoint{secret: "secret"}
+
+	d := newDispatcher(t, es)
+
+	d.Add(webhook.Subscriber{ID: "partner", URL: serve(t, &e), Secret: "secret"})
+
+	rctx, cancel := context.WithCancel(ctx)
+
+	defer cancel()
+
+	go func() { _ = d.Run(rctx) }()
+
+	assert.Eventually(t, delivered(d, "partner", 1), 5*time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, d.Disable(ctx, "partner"))
+
+	err = es.AppendStream(ctx, "stream-1", 1, []eventstore.EventToStore{
+		{Event: itemAdded{N: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	assert.Len(t, e.projected(), 1)
+
+	assert.NoError(t, d.Enable(ctx, "partner"))
+
+	assert.Eventually(t, func() bool { return len(e.projected()) == 2 }, 10*time.Second, 5*time.Millisecond)
+}
+
+func TestShouldKeepSubscriberDisabledOnFailures(t *testing.T) {
+	es := newEventStore(t)
+
+	ctx := context.Background()
+
+	err := es.AppendStream(ctx, "stream-1", 0, []eventstore.EventToStore{
+		{Event: itemAdded{N: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := endpoint{
+		secret: "secret",
+		respond: func(_ int) (int, string) {
+			return http.StatusServiceUnavailable, ""
+		},
+	}
+
+	d := newDispatcher(t, es, webhook.WithMaxFailures(10), webhook.WithBackoff(200*time.Millisecond, 200*time.Millisecond))
+
+	d.Add(webhook.Subscriber{ID: "partner", URL: serve(t, &e), Secret: "secret"})
+
+	rctx, cancel := context.WithCancel(ctx)
+
+	defer cancel()
+
+	go func() { _ = d.Run(rctx) }()
+
+	assert.Eventually(t, func() bool { return e.callCount() == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, d.Disable(ctx, "partner"))
+
+	assert.Eventually(t, func() bool {
+		state, err := d.State(ctx, "partner")
+
+		return err == nil && state.Failures == 2
+	}, 5*time.Second, 5*time.Millisecond)
+
+	time.Sleep(500 * time.Millisecond)
+
+	state, err := d.State(ctx, "partner")
+
+	assert.NoError(t, err)
+	assert.True(t, state.Disabled)
+	assert.Equal(t, 2, state.Failures)
+	assert.Equal(t, 2, e.callCount())
+}
+
+func TestShouldRejectDuplicateSubscribers(t *testing.T) {
+	d := newDispatcher(t, newEventStore(t))
+
+	d.Add(
+		webhook.Subscriber{ID: "partner", URL: "http://localhost"},
+		webhook.Subscriber{ID: "partner", URL: "http://localhost"},
+	)
+
+	assert.ErrorIs(t, d.Run(context.Background()), webhook.ErrDuplicateSubscriber)
+}